@@ -0,0 +1,57 @@
+package calm
+
+import "sync/atomic"
+
+var _AsyncTrace atomic.Bool
+
+// SetAsyncTrace
+// Enable or disable async stack stitching, disabled by default as it captures a stack for every Defer created.
+// When enabled, a Defer records the stack of its creation site. If the task fails, its error is nested with the
+// creation stack, and once more with the stack of the awaiting site when Get, Unwrap or Fold report it. PrintDetails
+// then renders the creator, task and awaiter stacks as one logical stack, the stitched slices are otherwise
+// transparent: they are skipped by Walk, the printed messages and the encoders.
+func SetAsyncTrace(enabled bool) { _AsyncTrace.Store(enabled) }
+
+// sInfoAsync slice carrying the stack of an async site, transparently forwarding the top slice of the stitched error
+type sInfoAsync struct {
+	inner ErrorInfo
+}
+
+func (e *sInfoAsync) TCode() uint32  { return e.inner.TCode() }
+func (e *sInfoAsync) ECode() uint32  { return e.inner.ECode() }
+func (e *sInfoAsync) Clean() string  { return e.inner.Clean() }
+func (e *sInfoAsync) Detail() string { return e.inner.Detail() }
+func (e *sInfoAsync) Meta() any      { return e.inner.Meta() }
+
+func _IsAsync(info ErrorInfo) bool {
+	_, ok := info.(*sInfoAsync)
+	return ok
+}
+
+// pCreatorTrace the stack of the creation site of a Defer, nil unless async stitching is enabled
+func pCreatorTrace() []uintptr {
+	if _AsyncTrace.Load() {
+		return pWithTrace()
+	}
+	return nil
+}
+
+// _Stitch nest err with a slice carrying trace, err as is if there is no trace
+func _Stitch(err Error, trace []uintptr) Error {
+	if (err == nil) || (trace == nil) {
+		return err
+	}
+	info := _TopInfo(err)
+	if async, ok := info.(*sInfoAsync); ok {
+		info = async.inner
+	}
+	return &sErrChain{sErrNode: sErrNode{info: &sInfoAsync{inner: info}, trace: trace}, next: err}
+}
+
+// pStitchAwait nest err with the stack of the awaiting site if async stitching is enabled
+func pStitchAwait(err Error) Error {
+	if _AsyncTrace.Load() {
+		return _Stitch(err, pWithTrace())
+	}
+	return err
+}
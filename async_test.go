@@ -0,0 +1,99 @@
+package calm
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func _AsyncCreator() Defer { return RunAsync(func() { ThrowClean(EResNone, "gone") }) }
+
+func _AsyncAwaiter(d Defer) Error { return Run(d.Get).Dump() }
+
+// _AsyncSlices the number of stitched slices in err
+func _AsyncSlices(err Error) (n int) {
+	slices, _ := _Unfold(err)
+	for _, slice := range slices {
+		if _IsAsync(slice.info) {
+			n++
+		}
+	}
+	return
+}
+
+func TestAsyncStitch(t *testing.T) {
+	SetAsyncTrace(true)
+	defer SetAsyncTrace(false)
+	err := _AsyncAwaiter(_AsyncCreator())
+	if (err == nil) || (err.ECode() != EResNone) || (_AsyncSlices(err) != 2) {
+		t.Fatalf("got %v, want the failure stitched with the creator and awaiter stacks", err)
+	}
+	var stacks []string
+	slices, _ := _Unfold(err)
+	for _, slice := range slices {
+		if _IsAsync(slice.info) {
+			var funcs []string
+			frames := runtime.CallersFrames(slice.trace)
+			for more := true; more; {
+				var frame runtime.Frame
+				frame, more = frames.Next()
+				funcs = append(funcs, frame.Function)
+			}
+			stacks = append(stacks, strings.Join(funcs, " "))
+		}
+	}
+	// the awaiter slice is on top of the creator one
+	for i, site := range []string{"calm._AsyncAwaiter", "calm._AsyncCreator"} {
+		if !strings.Contains(stacks[i], site) {
+			t.Errorf("%s missing from stitched stack %d: %s", site, i, stacks[i])
+		}
+	}
+	if cleans := PrintCleans(err); cleans != "resource: not found: gone\n" {
+		t.Errorf("stitched slices printed: %q", cleans)
+	}
+}
+
+func TestAsyncStitchHidden(t *testing.T) {
+	SetAsyncTrace(true)
+	defer SetAsyncTrace(false)
+	err := _AsyncAwaiter(_AsyncCreator())
+	visited := 0
+	Walk(err, func(int, ErrorInfo) bool {
+		visited++
+		return true
+	})
+	if visited != 1 {
+		t.Errorf("Walk visited %d slices, want 1", visited)
+	}
+	data, e := EncodeJSON(err, FullEncode)
+	if e != nil {
+		t.Fatal(e)
+	}
+	decoded, e := DecodeJSON(data)
+	if (e != nil) || (len(_WireSlices(decoded)) != 1) {
+		t.Errorf("JSON %s, %v, want a single slice", data, e)
+	}
+	if decoded, e = DecodeWire(EncodeWire(err, FullEncode)); (e != nil) || (len(_WireSlices(decoded)) != 1) {
+		t.Errorf("wire decoded %v, %v, want a single slice", decoded, e)
+	}
+}
+
+func TestAsyncStitchOnce(t *testing.T) {
+	SetAsyncTrace(true)
+	defer SetAsyncTrace(false)
+	d := _AsyncCreator()
+	for i := 0; i < 3; i++ {
+		if n := _AsyncSlices(_AsyncAwaiter(d)); n != 2 {
+			t.Errorf("await %d: %d stitched slices, want 2", i+1, n)
+		}
+	}
+	if n := _AsyncSlices(d.Dump()); n != 1 {
+		t.Errorf("stored error has %d stitched slices, want the creator one only", n)
+	}
+}
+
+func TestAsyncStitchDisabled(t *testing.T) {
+	if err := _AsyncAwaiter(_AsyncCreator()); _AsyncSlices(err) != 0 {
+		t.Errorf("stitched while disabled: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package calm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Key
+// Typed key of error attributes, see Lookup. Keys are matched by name, including against the attributes of remote
+// errors whose values are decoded from JSON, names should thus be unique across the processes exchanging errors.
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) Key[T] { return Key[T]{name: name} }
+
+func (k Key[T]) Name() string { return k.name }
+
+// Of an attribute of key k with value v
+func (k Key[T]) Of(v T) Attr { return Attr{Key: k.name, Value: v} }
+
+// Attr a key/value attribute of an error slice, created by Key.Of
+type Attr struct {
+	Key   string
+	Value any // json.RawMessage for attributes of remote errors
+}
+
+// ErrorAttrs
+// Optional interface of an ErrorInfo carrying attributes, implemented by the slices created with InfoAttr or ErrAttr.
+// On duplicate keys, the first attribute takes precedence.
+type ErrorAttrs interface {
+	Attrs() []Attr
+}
+
+type sInfoAttr struct {
+	ErrorInfo
+	attrs []Attr
+}
+
+func (e *sInfoAttr) Attrs() []Attr {
+	return append(append([]Attr(nil), e.attrs...), pAttrsSafe(e.ErrorInfo)...)
+}
+
+// InfoAttr info with attrs attached, taking precedence over the attributes info already has
+func InfoAttr(info ErrorInfo, attrs ...Attr) ErrorInfo {
+	if len(attrs) == 0 {
+		return info
+	}
+	if remote, ok := info.(*sInfoRemote); ok {
+		clone := *remote
+		clone.attrs = append(append([]Attr(nil), attrs...), remote.attrs...)
+		return &clone
+	}
+	return &sInfoAttr{ErrorInfo: info, attrs: append([]Attr(nil), attrs...)}
+}
+
+// ErrAttr
+// err with attrs attached to its top slice, see InfoAttr.
+// If the top slice cannot be replaced, e.g. for custom calm.Error implementations, a slice carrying attrs with the
+// same code is nested on top of err instead.
+func ErrAttr(err Error, attrs ...Attr) Error {
+	if (err == nil) || (len(attrs) == 0) {
+		return err
+	}
+	switch e := err.(type) {
+	case *sErrNode:
+		if !_IsAsync(e.info) {
+			clone := *e
+			clone.info = InfoAttr(e.info, attrs...)
+			return &clone
+		}
+	case *sErrChain:
+		if !_IsAsync(e.info) {
+			clone := *e
+			clone.info = InfoAttr(e.info, attrs...)
+			return &clone
+		}
+	case *sErrJoin:
+		if !_IsAsync(e.info) {
+			clone := *e
+			clone.info = InfoAttr(e.info, attrs...)
+			return &clone
+		}
+	}
+	top, _ := _ErrExtractNestPair(err)
+	return ErrNestByInfo(err, InfoAttr(InfoCode(MakeErrCode(top.TCode(), top.ECode())), attrs...))
+}
+
+func pAttrsSafe(info ErrorInfo) (res []Attr) {
+	defer func() {
+		if recover() != nil {
+			res = nil
+		}
+	}()
+	if attrs, ok := info.(ErrorAttrs); ok {
+		return attrs.Attrs()
+	}
+	return nil
+}
+
+// Lookup
+// The value of the first attribute of key found in err, searching every slice top-down as by Walk.
+// Values of remote attributes are decoded from JSON into T, attributes whose value is not a T are skipped.
+func Lookup[T any](err Error, key Key[T]) (res T, found bool) {
+	Walk(err, func(_ int, info ErrorInfo) bool {
+		for _, attr := range pAttrsSafe(info) {
+			if found {
+				break
+			}
+			if attr.Key == key.name {
+				res, found = _AttrValue[T](attr.Value)
+			}
+		}
+		return !found
+	})
+	return
+}
+
+func _AttrValue[T any](value any) (res T, ok bool) {
+	if res, ok = value.(T); ok {
+		return
+	}
+	if raw, isRaw := value.(json.RawMessage); isRaw {
+		ok = json.Unmarshal(raw, &res) == nil
+	}
+	return
+}
+
+// FindMeta the first Meta() of type T in err, searching every slice top-down as by Walk
+func FindMeta[T any](err Error) (res T, found bool) {
+	Walk(err, func(_ int, info ErrorInfo) bool {
+		if !found {
+			res, found = pMetaSafe(info).(T)
+		}
+		return !found
+	})
+	return
+}
+
+// _AttrsToWire the JSON values of attrs by key, the first attribute of a key taking precedence
+func _AttrsToWire(attrs []Attr) map[string]json.RawMessage {
+	if len(attrs) == 0 {
+		return nil
+	}
+	result := make(map[string]json.RawMessage, len(attrs))
+	for _, attr := range attrs {
+		if _, ok := result[attr.Key]; ok {
+			continue
+		}
+		if data := pJSONSafe(attr.Value); data != nil {
+			result[attr.Key] = data
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// _AttrsFromWire the attributes of a decoded slice, ordered by key
+func _AttrsFromWire(attrs map[string]json.RawMessage) []Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+	result := make([]Attr, 0, len(attrs))
+	for _, key := range _SortedKeys(attrs) {
+		result = append(result, Attr{Key: key, Value: attrs[key]})
+	}
+	return result
+}
+
+func _SortedKeys(attrs map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// _PrintAttrs print the attributes of info as ` {key=value, ...}`, the first attribute of a key taking precedence
+func _PrintAttrs(b *strings.Builder, info ErrorInfo) {
+	attrs := pAttrsSafe(info)
+	if len(attrs) == 0 {
+		return
+	}
+	seen := make(map[string]bool, len(attrs))
+	b.WriteString(" {")
+	for _, attr := range attrs {
+		if seen[attr.Key] {
+			continue
+		}
+		if len(seen) > 0 {
+			b.WriteString(", ")
+		}
+		seen[attr.Key] = true
+		b.WriteString(attr.Key)
+		b.WriteString("=")
+		if raw, ok := attr.Value.(json.RawMessage); ok {
+			b.Write(raw)
+		} else {
+			b.WriteString(pSprintSafe(attr.Value))
+		}
+	}
+	b.WriteString("}")
+}
+
+func pSprintSafe(v any) (res string) {
+	defer func() {
+		if recover() != nil {
+			res = "?"
+		}
+	}()
+	return fmt.Sprint(v)
+}
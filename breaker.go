@@ -0,0 +1,323 @@
+package calm
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState state of a CircuitBreaker
+type BreakerState int32
+
+const (
+	// BreakerClosed calls go through, failures are counted
+	BreakerClosed BreakerState = iota
+	// BreakerOpen calls fail fast with ENetDown until the open timeout elapses
+	BreakerOpen
+	// BreakerHalfOpen a limited number of probe calls go through to decide whether to close or open again
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig controls when a CircuitBreaker opens and closes
+type BreakerConfig struct {
+	// ConsecutiveFailures open after this many failures in a row, 0 to disable
+	ConsecutiveFailures int
+	// FailureRate open when the share of failures among the last Window calls reaches it, 0 to disable
+	FailureRate float64
+	// Window number of recent calls the failure rate is computed over, the rate is only checked once it is full
+	Window int
+	// OpenTimeout time spent open before letting probe calls through
+	OpenTimeout time.Duration
+	// HalfOpenProbes number of concurrent probe calls when half-open, all of them must succeed to close. At least 1
+	HalfOpenProbes int
+	// Counts decides which errors count as failures, BreakerFailure if nil. Other errors count as successes
+	Counts func(err Error) bool
+	// OnStateChange optional callback on every state change, called outside the breaker lock
+	OnStateChange func(from, to BreakerState)
+}
+
+// BreakerSnapshot point-in-time view of a CircuitBreaker, e.g. for health endpoints
+type BreakerSnapshot struct {
+	State BreakerState
+	// Since time of the last state change
+	Since time.Time
+	// Consecutive number of failures in a row
+	Consecutive int
+	// Failures and Samples within the failure rate window
+	Failures, Samples int
+	// LastError the last error counted as a failure, nil if none
+	LastError Error
+}
+
+// BreakerFailure
+// The default failure classification of a CircuitBreaker: system errors signalling an unavailable downstream, i.e. all
+// network codes, EStgQueue, EStgLost, EStgFail, EResRetry, EResFail and ETimeout.
+func BreakerFailure(err Error) bool {
+	if err.TCode() != 0 {
+		return false
+	}
+	switch code := err.ECode(); {
+	case (code >= ENetNone) && (code <= ENetFail):
+		return true
+	case code == EStgQueue, code == EStgLost, code == EStgFail:
+		return true
+	case code == EResRetry, code == EResFail, code == ETimeout:
+		return true
+	}
+	return false
+}
+
+// CircuitBreaker
+// Fail fast with ENetDown while a downstream keeps failing, instead of piling up calls on it.
+// Closed, calls go through and failures are counted. Once a threshold is reached it opens, and calls fail fast for
+// the open timeout. It then turns half-open, and lets probe calls through to decide whether to close or open again.
+type CircuitBreaker struct {
+	m           sync.Mutex
+	config      BreakerConfig
+	state       BreakerState
+	since       time.Time
+	gen         uint64 // incremented on every state change, outcomes of calls admitted earlier are dropped
+	consecutive int
+	window      []bool // ring buffer of the recent outcomes, true for a failure
+	pos, filled int
+	failures    int
+	probes, oks int
+	last        Error
+}
+
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.HalfOpenProbes < 1 {
+		config.HalfOpenProbes = 1
+	}
+	if config.Counts == nil {
+		config.Counts = BreakerFailure
+	}
+	b := &CircuitBreaker{config: config, since: time.Now()}
+	if config.Window > 0 {
+		b.window = make([]bool, config.Window)
+	}
+	return b
+}
+
+// Run run exec through the breaker, see RunResult
+func (b *CircuitBreaker) Run(exec func()) Result {
+	return b.RunResult(func() Result { return Run(exec) })
+}
+
+// RunResult
+// Run exec through the breaker, recording its outcome.
+// Fails fast with ENetDown, nesting the last recorded failure if any, while the breaker is open.
+func (b *CircuitBreaker) RunResult(exec func() Result) Result {
+	gen, err := b.pAcquire()
+	if err != nil {
+		return ErrResult(err)
+	}
+	normal := false
+	defer func() {
+		if !normal {
+			b.pRelease(gen) // exec called runtime.Goexit
+		}
+	}()
+	res := Run(func() { exec().Get() })
+	b.pRecord(gen, res.err)
+	normal = true
+	return res
+}
+
+// BreakerRunT Equivalent of b.Run for a task producing a value
+func BreakerRunT[T any](b *CircuitBreaker, exec func() T) ResultT[T] {
+	var val T
+	res := b.Run(func() { val = exec() })
+	return ResultT[T]{val: val, Result: res}
+}
+
+// BreakerAsyncT
+// Start the task returned by exec through the breaker, recording its outcome once it completes.
+// Fails fast with ENetDown, nesting the last recorded failure if any, while the breaker is open.
+func BreakerAsyncT[T any](b *CircuitBreaker, exec func() DeferT[T]) DeferT[T] {
+	gen, err := b.pAcquire()
+	if err != nil {
+		return ErrDeferT[T](err)
+	}
+	normal := false
+	defer func() {
+		if !normal {
+			b.pRelease(gen) // exec called runtime.Goexit
+		}
+	}()
+	started := RunT(exec)
+	normal = true
+	if started.err != nil {
+		b.pRecord(gen, started.err)
+		return ErrDeferT[T](started.err)
+	}
+	return Then(started.val, func(r ResultT[T]) T {
+		b.pRecord(gen, r.err)
+		return r.Get()
+	})
+}
+
+// State the current state
+func (b *CircuitBreaker) State() BreakerState {
+	b.m.Lock()
+	from := b.state
+	b.pTick()
+	to := b.state
+	b.m.Unlock()
+	b.pNotify(from, to)
+	return to
+}
+
+// Snapshot a point-in-time view of the breaker
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.m.Lock()
+	from := b.state
+	b.pTick()
+	snap := BreakerSnapshot{
+		State: b.state, Since: b.since, Consecutive: b.consecutive,
+		Failures: b.failures, Samples: b.filled, LastError: b.last,
+	}
+	b.m.Unlock()
+	b.pNotify(from, snap.State)
+	return snap
+}
+
+// pTick turn half-open once the open timeout elapsed, the lock must be held
+func (b *CircuitBreaker) pTick() {
+	if (b.state == BreakerOpen) && (time.Since(b.since) >= b.config.OpenTimeout) {
+		b.pMove(BreakerHalfOpen)
+	}
+}
+
+func (b *CircuitBreaker) pAcquire() (uint64, Error) {
+	b.m.Lock()
+	from := b.state
+	b.pTick()
+	to := b.state
+	gen := b.gen
+	admit := true
+	switch b.state {
+	case BreakerOpen:
+		admit = false
+	case BreakerHalfOpen:
+		if admit = b.probes < b.config.HalfOpenProbes; admit {
+			b.probes++
+		}
+	}
+	last := b.last
+	b.m.Unlock()
+	b.pNotify(from, to)
+	if admit {
+		return gen, nil
+	}
+	if last != nil {
+		return 0, ErrCleanN(last, ENetDown, "circuit open")
+	}
+	return 0, ErrClean(ENetDown, "circuit open")
+}
+
+func (b *CircuitBreaker) pRecord(gen uint64, err Error) {
+	failed := (err != nil) && pCountsSafe(b.config.Counts, err)
+	b.m.Lock()
+	from := b.state
+	if gen == b.gen {
+		if failed {
+			b.last = err
+		}
+		switch b.state {
+		case BreakerClosed:
+			b.pSample(failed)
+			if b.pTripped() {
+				b.pMove(BreakerOpen)
+			}
+		case BreakerHalfOpen:
+			if failed {
+				b.pMove(BreakerOpen)
+			} else if b.oks++; b.oks >= b.config.HalfOpenProbes {
+				b.pMove(BreakerClosed)
+			}
+		}
+	}
+	to := b.state
+	b.m.Unlock()
+	b.pNotify(from, to)
+}
+
+// pRelease free the probe slot of a call admitted by pAcquire that ended without an outcome
+func (b *CircuitBreaker) pRelease(gen uint64) {
+	b.m.Lock()
+	if (gen == b.gen) && (b.state == BreakerHalfOpen) && (b.probes > 0) {
+		b.probes--
+	}
+	b.m.Unlock()
+}
+
+func pCountsSafe(counts func(err Error) bool, err Error) (res bool) {
+	defer func() {
+		if recover() != nil {
+			res = true
+		}
+	}()
+	return counts(err)
+}
+
+func (b *CircuitBreaker) pSample(failed bool) {
+	if failed {
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+	if b.window == nil {
+		return
+	}
+	if b.filled == len(b.window) {
+		if b.window[b.pos] {
+			b.failures--
+		}
+	} else {
+		b.filled++
+	}
+	b.window[b.pos] = failed
+	if failed {
+		b.failures++
+	}
+	b.pos = (b.pos + 1) % len(b.window)
+}
+
+func (b *CircuitBreaker) pTripped() bool {
+	if (b.config.ConsecutiveFailures > 0) && (b.consecutive >= b.config.ConsecutiveFailures) {
+		return true
+	}
+	if (b.config.FailureRate > 0) && (b.window != nil) && (b.filled == len(b.window)) {
+		return float64(b.failures)/float64(b.filled) >= b.config.FailureRate
+	}
+	return false
+}
+
+// pMove change the state and reset the counters of the new state, the lock must be held
+func (b *CircuitBreaker) pMove(to BreakerState) {
+	b.state = to
+	b.since = time.Now()
+	b.gen++
+	b.probes, b.oks = 0, 0
+	if to == BreakerClosed {
+		b.consecutive, b.pos, b.filled, b.failures = 0, 0, 0, 0
+	}
+}
+
+func (b *CircuitBreaker) pNotify(from, to BreakerState) {
+	if (from != to) && (b.config.OnStateChange != nil) {
+		b.config.OnStateChange(from, to)
+	}
+}
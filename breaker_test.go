@@ -0,0 +1,56 @@
+package calm
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// _AwaitState poll the state of b until it reaches want, failing the test if it does not within a few seconds
+func _AwaitState(t *testing.T, b *CircuitBreaker, want BreakerState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for state := b.State(); state != want; state = b.State() {
+		if time.Now().After(deadline) {
+			t.Fatalf("state %v, want %v", state, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBreakerStateNotifies(t *testing.T) {
+	var m sync.Mutex
+	var changes []BreakerState
+	b := NewCircuitBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: 10 * time.Millisecond,
+		OnStateChange: func(from, to BreakerState) {
+			m.Lock()
+			changes = append(changes, to)
+			m.Unlock()
+		}})
+	b.Run(func() { ThrowClean(ENetDown, "down") })
+	_AwaitState(t, b, BreakerHalfOpen)
+	m.Lock()
+	defer m.Unlock()
+	if (len(changes) != 2) || (changes[0] != BreakerOpen) || (changes[1] != BreakerHalfOpen) {
+		t.Errorf("changes %v, want [open half-open]", changes)
+	}
+}
+
+func TestBreakerGoexitReleasesProbe(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Millisecond})
+	b.Run(func() { ThrowClean(ENetDown, "down") })
+	_AwaitState(t, b, BreakerHalfOpen)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Run(runtime.Goexit)
+	}()
+	<-done
+	if err := b.Run(func() {}).Dump(); err != nil {
+		t.Fatalf("probe slot leaked: %v", err)
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("state %v, want closed", state)
+	}
+}
@@ -0,0 +1,137 @@
+package calm
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+type sUnhandledRef struct{ h func(Error) }
+
+var _UnhandledHandler atomic.Pointer[sUnhandledRef]
+
+// SetUnhandledHandler
+// Set the handler of errors that have nowhere else to go, e.g. panics of completion callbacks.
+// nil restores the default handler, printing the error with PrintDetails to the standard error.
+func SetUnhandledHandler(h func(Error)) {
+	if h == nil {
+		_UnhandledHandler.Store(nil)
+		return
+	}
+	_UnhandledHandler.Store(&sUnhandledRef{h: h})
+}
+
+func _DefaultUnhandled(err Error) {
+	_, _ = fmt.Fprint(os.Stderr, "calm: unhandled error\n", PrintDetails(err, TrimFullPrint))
+}
+
+func pReportUnhandled(err Error) {
+	defer func() { _ = recover() }()
+	if ref := _UnhandledHandler.Load(); ref != nil {
+		ref.h(err)
+	} else {
+		_DefaultUnhandled(err)
+	}
+}
+
+// pFire mark the Defer as fired and run the registered callbacks, once err is final
+func (s *sDefer) pFire() {
+	s.m.Lock()
+	s.fired = true
+	callbacks := s.callbacks
+	s.callbacks = nil
+	s.m.Unlock()
+	for _, f := range callbacks {
+		f()
+	}
+}
+
+// pOnDone run f once the Defer completed, immediately if it already is. f must not panic
+func (c Defer) pOnDone(f func()) {
+	s := c.pState()
+	s.pObserve()
+	s.m.Lock()
+	if !s.fired {
+		s.callbacks = append(s.callbacks, f)
+		s.m.Unlock()
+		return
+	}
+	s.m.Unlock()
+	f()
+}
+
+// pDispatch run f on executor if not nil, inline otherwise, reporting its failure as unhandled
+func pDispatch(f func(), executor Executor) {
+	run := func() {
+		normal := false
+		defer func() {
+			if err := pRecovered(recover(), normal); err != nil {
+				pReportUnhandled(ErrCleanN(err, EInternal, "completion callback failed"))
+			}
+		}()
+		f()
+		normal = true
+	}
+	if executor == nil {
+		run()
+	} else if err := pExecuteSafe(executor, run); err != nil {
+		pReportUnhandled(ErrCleanN(err, EInternal, "completion callback rejected"))
+	}
+}
+
+// OnComplete
+// Call f with the error of the task, nil on success, once it completes. If it already did, f is called immediately.
+// f runs on the goroutine completing the task, or registering f, see OnCompleteOn to run it on an Executor.
+// Panics of f are reported to the handler set by SetUnhandledHandler.
+func (c Defer) OnComplete(f func(Error)) { c.OnCompleteOn(nil, f) }
+
+// OnCompleteOn Equivalent of OnComplete, running f on e unless nil. Rejections of e are reported as unhandled
+func (c Defer) OnCompleteOn(e Executor, f func(Error)) {
+	c.pOnDone(func() { pDispatch(func() { f(c.pState().err) }, e) })
+}
+
+// OnSuccess Equivalent of OnComplete, only calling f if the task succeeded
+func (c Defer) OnSuccess(f func()) { c.OnSuccessOn(nil, f) }
+
+// OnSuccessOn Equivalent of OnCompleteOn, only calling f if the task succeeded
+func (c Defer) OnSuccessOn(e Executor, f func()) {
+	c.pOnDone(func() {
+		if c.pState().err == nil {
+			pDispatch(f, e)
+		}
+	})
+}
+
+// OnError Equivalent of OnComplete, only calling f if the task failed
+func (c Defer) OnError(f func(Error)) { c.OnErrorOn(nil, f) }
+
+// OnErrorOn Equivalent of OnCompleteOn, only calling f if the task failed
+func (c Defer) OnErrorOn(e Executor, f func(Error)) {
+	c.pOnDone(func() {
+		if err := c.pState().err; err != nil {
+			pDispatch(func() { f(err) }, e)
+		}
+	})
+}
+
+// OnComplete Equivalent of Defer.OnComplete, calling f with the outcome of the task
+func (c DeferT[T]) OnComplete(f func(ResultT[T])) { c.OnCompleteOn(nil, f) }
+
+// OnCompleteOn Equivalent of Defer.OnCompleteOn, calling f with the outcome of the task
+func (c DeferT[T]) OnCompleteOn(e Executor, f func(ResultT[T])) {
+	c.pOnDone(func() {
+		pDispatch(func() { f(ResultT[T]{val: c.pVal(), Result: Result{err: c.pState().err}}) }, e)
+	})
+}
+
+// OnSuccess Equivalent of Defer.OnSuccess, calling f with the value of the task
+func (c DeferT[T]) OnSuccess(f func(T)) { c.OnSuccessOn(nil, f) }
+
+// OnSuccessOn Equivalent of Defer.OnSuccessOn, calling f with the value of the task
+func (c DeferT[T]) OnSuccessOn(e Executor, f func(T)) {
+	c.pOnDone(func() {
+		if c.pState().err == nil {
+			pDispatch(func() { f(c.pVal()) }, e)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package calm
+
+import "testing"
+
+// _ExecutorFunc an Executor calling a function
+type _ExecutorFunc func(task func()) Error
+
+func (f _ExecutorFunc) Execute(task func()) Error { return f(task) }
+
+func TestCallbackExecutor(t *testing.T) {
+	unhandled := make(chan Error, 1)
+	SetUnhandledHandler(func(err Error) { unhandled <- err })
+	defer SetUnhandledHandler(nil)
+	submitted := 0
+	counting := _ExecutorFunc(func(task func()) Error {
+		submitted++
+		task()
+		return nil
+	})
+	d := RunAsyncT(func() int { return 7 })
+	d.Wait()
+	got := 0
+	d.OnSuccessOn(counting, func(v int) { got = v })
+	d.OnCompleteOn(nil, func(r ResultT[int]) { got += r.Get() })
+	if (got != 14) || (submitted != 1) {
+		t.Errorf("got %d with %d tasks submitted, want 14 with 1", got, submitted)
+	}
+	rejecting := _ExecutorFunc(func(func()) Error { return ErrClean(EBacklog, "full") })
+	d.OnCompleteOn(rejecting, func(ResultT[int]) { t.Error("callback run despite the rejection") })
+	if err := <-unhandled; err.ECode() != EInternal {
+		t.Errorf("rejection reported as %v", err)
+	}
+	d.OnSuccess(func(int) { panic("callback bug") })
+	if err := <-unhandled; err.ECode() != EInternal {
+		t.Errorf("panic reported as %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package calm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"net"
+	"sync"
+)
+
+// Classifier maps an error onto a calm error code, reporting false if it does not recognise the error
+type Classifier func(err error) (code uint64, ok bool)
+
+var (
+	_ClassifyLock sync.RWMutex
+	_Classifiers  []Classifier
+)
+
+// AddClassifier register c, consulted by ClassifyCode before the classifiers registered earlier and the built-in one
+func AddClassifier(c Classifier) {
+	_ClassifyLock.Lock()
+	defer _ClassifyLock.Unlock()
+	_Classifiers = append([]Classifier{c}, _Classifiers...)
+}
+
+// ClassifyCode
+// Map err onto a calm error code with the registered classifiers, then the built-in one.
+// The built-in classifier recognises, through errors.Is and errors.As:
+// context.Canceled as ECancel, context.DeadlineExceeded as ETimeout, fs.ErrNotExist as EStgNone,
+// fs.ErrPermission as EDenied, ENOSPC and EDQUOT as EStgFull, sql.ErrNoRows as EResNone,
+// name resolution failures as ENetNone, ECONNREFUSED, ECONNRESET and other net.OpError as ENetDown,
+// and other net.Error timeouts as ENetLost. Reports false if no classifier recognises err.
+func ClassifyCode(err error) (uint64, bool) {
+	_ClassifyLock.RLock()
+	classifiers := _Classifiers
+	_ClassifyLock.RUnlock()
+	for _, c := range classifiers {
+		if code, ok := pClassifySafe(c, err); ok {
+			return code, true
+		}
+	}
+	return _ClassifyStd(err)
+}
+
+// Classify
+// Equivalent of ErrErr(code, err) with the code from ClassifyCode, or EInternal if err is not recognised.
+// A calm.Error found in the chain of err by errors.As is returned as is, and nil for a nil err.
+func Classify(err error) Error {
+	if err == nil {
+		return nil
+	}
+	var typed Error
+	if errors.As(err, &typed) {
+		return typed
+	}
+	code, ok := ClassifyCode(err)
+	if !ok {
+		code = EInternal
+	}
+	return ErrErr(code, err)
+}
+
+func pClassifySafe(c Classifier, err error) (code uint64, ok bool) {
+	defer func() {
+		if recover() != nil {
+			code, ok = 0, false
+		}
+	}()
+	return c(err)
+}
+
+func _ClassifyStd(err error) (uint64, bool) {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ECancel, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return ETimeout, true
+	case errors.Is(err, fs.ErrNotExist):
+		return EStgNone, true
+	case errors.Is(err, fs.ErrPermission):
+		return EDenied, true
+	case _IsAny(err, _ErrnoFull):
+		return EStgFull, true
+	case errors.Is(err, sql.ErrNoRows):
+		return EResNone, true
+	case errors.As(err, &dnsErr):
+		return ENetNone, true
+	case _IsAny(err, _ErrnoDown):
+		return ENetDown, true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ENetLost, true
+	case errors.As(err, &opErr):
+		return ENetDown, true
+	}
+	return 0, false
+}
+
+func _IsAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
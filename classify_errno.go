@@ -0,0 +1,12 @@
+//go:build !plan9
+
+package calm
+
+import "syscall"
+
+var (
+	// _ErrnoFull system errors classified as EStgFull
+	_ErrnoFull = []error{syscall.ENOSPC, syscall.EDQUOT}
+	// _ErrnoDown system errors classified as ENetDown
+	_ErrnoDown = []error{syscall.ECONNREFUSED, syscall.ECONNRESET}
+)
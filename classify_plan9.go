@@ -0,0 +1,8 @@
+package calm
+
+// plan9 reports system errors as strings, with no portable values to classify
+
+var (
+	_ErrnoFull []error
+	_ErrnoDown []error
+)
@@ -0,0 +1,88 @@
+package calm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+)
+
+type _ClassifyCase struct {
+	err  error
+	code uint64
+}
+
+func TestClassifyStd(t *testing.T) {
+	_, notExist := os.Open("/calm/test/does/not/exist")
+	cases := []_ClassifyCase{
+		{context.Canceled, ECancel},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), ETimeout},
+		{notExist, EStgNone},
+		{&os.PathError{Op: "open", Path: "/etc/shadow", Err: os.ErrPermission}, EDenied},
+		{fmt.Errorf("user 42: %w", sql.ErrNoRows), EResNone},
+		{&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "db.invalid"}}, ENetNone},
+		{&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, ENetLost},
+		{&net.OpError{Op: "dial", Err: errors.New("unreachable")}, ENetDown},
+	}
+	for _, errno := range _ErrnoFull {
+		cases = append(cases, _ClassifyCase{os.NewSyscallError("write", errno), EStgFull})
+	}
+	for _, errno := range _ErrnoDown {
+		cases = append(cases, _ClassifyCase{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", errno)}, ENetDown})
+	}
+	for _, c := range cases {
+		if code, ok := ClassifyCode(c.err); !ok || (code != c.code) {
+			t.Errorf("%v classified as %d %v, want %d", c.err, code, ok, c.code)
+		}
+	}
+	if code, ok := ClassifyCode(errors.New("unknown")); ok {
+		t.Errorf("unknown error classified as %d", code)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	if Classify(nil) != nil {
+		t.Error("nil classified")
+	}
+	typed := ErrClean(EResGone, "gone")
+	if Classify(fmt.Errorf("lookup: %w", typed)) != typed {
+		t.Error("calm.Error in the chain not returned as is")
+	}
+	if err := Classify(errors.New("unknown")); err.ECode() != EInternal {
+		t.Errorf("unknown error classified as %v", err)
+	}
+	if err := Classify(context.Canceled); (err.ECode() != ECancel) || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want ECancel wrapping context.Canceled", err)
+	}
+}
+
+// _ClassifyTestErr error only recognised by the classifiers registered in TestClassifierPrecedence
+type _ClassifyTestErr struct{ error }
+
+func TestClassifierPrecedence(t *testing.T) {
+	match := func(code uint64) Classifier {
+		return func(err error) (uint64, bool) {
+			var target *_ClassifyTestErr
+			return code, errors.As(err, &target)
+		}
+	}
+	AddClassifier(match(EResNone))
+	AddClassifier(match(EResGone))
+	AddClassifier(func(err error) (uint64, bool) {
+		var target *_ClassifyTestErr
+		if errors.As(err, &target) {
+			panic("broken classifier")
+		}
+		return 0, false
+	})
+	err := &_ClassifyTestErr{context.Canceled}
+	if code, ok := ClassifyCode(err); !ok || (code != EResGone) {
+		t.Errorf("classified as %d %v, want the latest registered classifier", code, ok)
+	}
+	if code, _ := ClassifyCode(context.Canceled); code != ECancel {
+		t.Errorf("unrelated error classified as %d", code)
+	}
+}
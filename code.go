@@ -144,6 +144,11 @@ type IErrType interface {
 	DefaultMsg(err uint32) string
 }
 
+// IErrRetry optional interface of a registered IErrType declaring which of its error codes are worth retrying
+type IErrRetry interface {
+	Retryable(err uint32) bool
+}
+
 type _ErrSys struct{}
 
 func (e *_ErrSys) Type() uint32              { return 0 }
@@ -152,6 +157,14 @@ func (e *_ErrSys) OnErrRoot(err Error) bool  { return err.ECode() == EInternal }
 func (e *_ErrSys) ErrName(err uint32) string { return _ErrSysMsg[err] }
 func (e *_ErrSys) DefaultMsg(uint32) string  { return "" }
 
+func (e *_ErrSys) Retryable(err uint32) bool {
+	switch err {
+	case EStgQueue, ENetQueue, ENetRetry, EResRetry, EBacklog:
+		return true
+	}
+	return false
+}
+
 type _ErrNil struct{ uint32 }
 
 func (e _ErrNil) Type() uint32             { return e.uint32 }
@@ -175,6 +188,9 @@ func MakeErrCode(typeCode uint32, errCode uint32) uint64 {
 	return (uint64(typeCode) << uint64(32)) | uint64(errCode)
 }
 
+// ErrType the calm.IErrType registered for a type code, or a placeholder with empty names and messages
+func ErrType(tCode uint32) IErrType { return fGetErrType(tCode) }
+
 func fGetErrType(tCode uint32) IErrType {
 	if reg, ok := _Reg.Load(tCode); ok {
 		return reg.(IErrType)
@@ -194,6 +210,14 @@ func pErrOnRootSafe(tCode uint32, err Error) (res bool) {
 	return
 }
 
+func pErrRetryableSafe(tCode uint32, eCode uint32) (res bool) {
+	defer func() { _ = recover() }()
+	if reg, ok := fGetErrType(tCode).(IErrRetry); ok {
+		res = reg.Retryable(eCode)
+	}
+	return
+}
+
 func pErrDefaultMsgSafe(tCode uint32, eCode uint32) (res string) {
 	defer func() { _ = recover() }()
 	res = fGetErrType(tCode).DefaultMsg(eCode)
@@ -0,0 +1,133 @@
+// Package debug serves the introspection of calm over HTTP, in the manner of net/http/pprof.
+// Importing it registers Index at /debug/calm on net/http.DefaultServeMux. Nothing is tracked unless enabled with
+// calm.TrackPending and calm.RecordRecent.
+package debug
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DWVoid/calm"
+)
+
+func init() {
+	http.HandleFunc("/debug/calm", Index)
+}
+
+// Snapshot the introspection data served by Index
+type Snapshot struct {
+	Time    time.Time           `json:"time"`
+	Pending []calm.PendingDefer `json:"pending"`
+	Recent  []Recent            `json:"recent"`
+	Codes   []CodeCount         `json:"codes"`
+}
+
+// Recent a recent root error, see calm.RecentErrors
+type Recent struct {
+	Time    time.Time       `json:"time"`
+	Type    uint32          `json:"type"`
+	Code    uint32          `json:"code"`
+	Message string          `json:"message"`
+	Error   json.RawMessage `json:"error"`
+}
+
+// CodeCount number of recent root errors with the same code, in decreasing order of count
+type CodeCount struct {
+	Type  uint32 `json:"type"`
+	Code  uint32 `json:"code"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+var _RecentEncode = &calm.EncodeOptions{Frames: true}
+
+// Collect take a snapshot of the pending Defers and the recent root errors
+func Collect() *Snapshot {
+	snap := &Snapshot{Time: time.Now(), Pending: calm.PendingDefers()}
+	counts := map[uint64]*CodeCount{}
+	for _, recent := range calm.RecentErrors() {
+		err := recent.Err
+		item := Recent{Time: recent.Time, Type: err.TCode(), Code: err.ECode()}
+		item.Message = strings.TrimSuffix(calm.PrintCleans(err), "\n")
+		item.Error, _ = calm.EncodeJSON(err, _RecentEncode)
+		snap.Recent = append(snap.Recent, item)
+		key := calm.MakeErrCode(item.Type, item.Code)
+		if count, ok := counts[key]; ok {
+			count.Count++
+		} else {
+			counts[key] = &CodeCount{Type: item.Type, Code: item.Code, Name: pErrNameSafe(item.Type, item.Code), Count: 1}
+		}
+	}
+	for _, count := range counts {
+		snap.Codes = append(snap.Codes, *count)
+	}
+	sort.Slice(snap.Codes, func(i, j int) bool {
+		if snap.Codes[i].Count != snap.Codes[j].Count {
+			return snap.Codes[i].Count > snap.Codes[j].Count
+		}
+		return calm.MakeErrCode(snap.Codes[i].Type, snap.Codes[i].Code) <
+			calm.MakeErrCode(snap.Codes[j].Type, snap.Codes[j].Code)
+	})
+	return snap
+}
+
+func pErrNameSafe(tCode, eCode uint32) (res string) {
+	defer func() { _ = recover() }()
+	return calm.ErrType(tCode).ErrName(eCode)
+}
+
+// Index
+// Serve a snapshot of the pending Defers and the recent root errors, as JSON if the format query parameter is json
+// or if the request accepts application/json, as an HTML page otherwise.
+func Index(w http.ResponseWriter, r *http.Request) {
+	snap := Collect()
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+	if (r.URL.Query().Get("format") == "json") || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(snap)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = _Page.Execute(w, snap)
+}
+
+var _Page = template.Must(template.New("calm").Funcs(template.FuncMap{
+	"age":  func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"when": func(t time.Time) string { return t.Format("15:04:05.000") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>/debug/calm</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 2px 8px; text-align: left; vertical-align: top; }
+pre { margin: 0; }
+</style>
+</head>
+<body>
+<p>Snapshot at {{when .Time}}, <a href="?format=json">JSON</a></p>
+<h2>Pending defers ({{len .Pending}})</h2>
+<table>
+<tr><th>Age</th><th>Label</th><th>Executor</th><th>Created at</th></tr>
+{{range .Pending}}<tr><td>{{age .Age}}</td><td>{{.Label}}</td><td>{{.Executor}}</td><td><pre>{{range .Stack}}{{.Func}} at ({{.Line}}:{{.File}})
+{{end}}</pre></td></tr>
+{{end}}</table>
+<h2>Recent error codes</h2>
+<table>
+<tr><th>Count</th><th>Type</th><th>Code</th><th>Name</th></tr>
+{{range .Codes}}<tr><td>{{.Count}}</td><td>{{.Type}}</td><td>{{.Code}}</td><td>{{.Name}}</td></tr>
+{{end}}</table>
+<h2>Recent errors ({{len .Recent}})</h2>
+<table>
+<tr><th>Time</th><th>Error</th></tr>
+{{range .Recent}}<tr><td>{{when .Time}}</td><td><pre>{{.Message}}</pre></td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
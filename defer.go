@@ -1,142 +1,327 @@
 package calm
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
+type sDefer struct {
+	err       Error
+	done      chan struct{}
+	fin       atomic.Bool
+	m         sync.Mutex
+	fired     bool      // set once err is final, callbacks registered later run immediately
+	callbacks []func()  // run in registration order on completion, must not panic
+	creator   []uintptr // stack of the creation site, see SetAsyncTrace
+	id        uint64    // non-zero if tracked, see TrackUnobserved
+	created   []uintptr // stack of the creation site if tracked or pending
+	observed  atomic.Bool
+	pending   bool // listed by PendingDefers until completed, see TrackPending
+	since     time.Time
+	label     string
+	executor  string
+	runner    atomic.Int64 // id of the goroutine running the task, see SetWaitWatchdog
+}
+
+type sDeferT[T any] struct {
+	sDefer // must stay the first field, the Defer view points to it
+	val    T
+}
+
+// Defer
+// Handle to the completion of an asynchronous task.
+// A Defer is a shared handle, it is safe to copy, send through channels and store in structs.
+// The zero Defer is a completed success.
 type Defer struct {
-	err  Error
-	done uint32
-	m    sync.Mutex
+	s *sDefer
 }
 
+// DeferT
+// Handle to the completion of an asynchronous task producing a value of T.
+// A DeferT is a shared handle, it is safe to copy, send through channels and store in structs.
+// The zero DeferT is a completed success with the zero value of T. A DeferT assembled from a Defer, as in
+// DeferT[T]{Defer: d}, or whose Defer was reassigned, follows the completion of that Defer with the zero value of T.
 type DeferT[T any] struct {
-	val T
 	Defer
+	t *sDeferT[T]
+}
+
+var _DeferNil = func() *sDefer {
+	s := &sDefer{done: make(chan struct{}), fired: true}
+	s.fin.Store(true)
+	close(s.done)
+	return s
+}()
+
+func pMakeDefer() Defer {
+	s := &sDefer{done: make(chan struct{}), creator: pCreatorTrace()}
+	s.pTrack()
+	return Defer{s: s}
+}
+
+func pMakeDeferT[T any]() DeferT[T] {
+	t := &sDeferT[T]{sDefer: sDefer{done: make(chan struct{}), creator: pCreatorTrace()}}
+	t.sDefer.pTrack()
+	return DeferT[T]{Defer: Defer{s: &t.sDefer}, t: t}
+}
+
+func (c Defer) pState() *sDefer {
+	if c.s == nil {
+		return _DeferNil
+	}
+	return c.s
+}
+
+// pTyped the state holding the value, nil unless c was created by pMakeDeferT and its Defer was left as is
+func (c DeferT[T]) pTyped() *sDeferT[T] {
+	if (c.t != nil) && (c.s == &c.t.sDefer) {
+		return c.t
+	}
+	return nil
+}
+
+func (c DeferT[T]) pVal() (res T) {
+	if t := c.pTyped(); t != nil {
+		res = t.val
+	}
+	return
+}
+
+// pComplete settle the Defer with err, only the first call takes effect
+func (c Defer) pComplete(err Error) bool {
+	s := c.pState()
+	if !s.fin.CompareAndSwap(false, true) {
+		return false
+	}
+	s.err = _Stitch(err, s.creator)
+	s.pCheckObserved()
+	s.pUnregisterPending()
+	close(s.done)
+	s.pFire()
+	return true
+}
+
+// pComplete settle the DeferT with v or err, only the first call takes effect
+func (c DeferT[T]) pComplete(v T, err Error) bool {
+	s := c.pState()
+	if !s.fin.CompareAndSwap(false, true) {
+		return false
+	}
+	if t := c.pTyped(); (err == nil) && (t != nil) {
+		t.val = v
+	}
+	s.err = _Stitch(err, s.creator)
+	s.pCheckObserved()
+	s.pUnregisterPending()
+	close(s.done)
+	s.pFire()
+	return true
 }
 
 func ValDefer() Defer {
-	return Defer{err: nil, done: 1, m: sync.Mutex{}}
+	ret := pMakeDefer()
+	ret.pComplete(nil)
+	return ret
 }
 
 func ErrDefer(err error) Defer {
-	return Defer{err: _AnyToError(err), done: 1, m: sync.Mutex{}}
+	ret := pMakeDefer()
+	ret.pComplete(_AnyToError(err))
+	return ret
 }
 
 func ErrDeferT[T any](err error) DeferT[T] {
-	return DeferT[T]{Defer: ErrDefer(err)}
+	var zero T
+	ret := pMakeDeferT[T]()
+	ret.pComplete(zero, _AnyToError(err))
+	return ret
 }
 
 func ValDeferT[T any](v T) DeferT[T] {
-	return DeferT[T]{val: v, Defer: ValDefer()}
+	ret := pMakeDeferT[T]()
+	ret.pComplete(v, nil)
+	return ret
 }
 
-func (c *Defer) realWait() {
-	c.m.Lock()
-	c.m.Unlock()
+// Wait
+// Wait for the task to complete, without reporting its outcome.
+// Throws the EInternal error of a wait cycle instead of deadlocking if detected, see SetWaitWatchdog.
+func (c Defer) Wait() {
+	if err := c.pState().pWait(); err != nil {
+		Throw(err)
+	}
+}
+
+// Done returns a channel that is closed when the task completes, for use in select statements
+func (c Defer) Done() <-chan struct{} {
+	s := c.pState()
+	s.pObserve()
+	return s.done
 }
 
-func (c *Defer) Wait() {
-	if atomic.LoadUint32(&c.done) == 0 {
-		// Outlined slow-path to allow inlining of the fast-path.
-		c.realWait()
+// WaitCtx
+// Wait for the task to complete or ctx to end, whichever happens first.
+// Returns an ETimeout (deadline exceeded) or ECancel error nesting the cause of ctx if it ended first, nil otherwise.
+// The outcome of the task itself is not reported, use Dump or Get once WaitCtx returned nil.
+func (c Defer) WaitCtx(ctx context.Context) Error {
+	done := c.Done()
+	select {
+	case <-done:
+		return nil
+	default:
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return pCtxError(ctx)
 	}
 }
 
-func (c *DeferT[T]) Wait() {
-	c.Defer.Wait()
+// GetCtx Equivalent of Get, but raises the error of WaitCtx if ctx ended before the task completed
+func (c Defer) GetCtx(ctx context.Context) {
+	if err := c.WaitCtx(ctx); err != nil {
+		Throw(err)
+	}
+	c.Get()
 }
 
-func (c *Defer) Unwrap(onError func(Error)) {
-	c.Wait()
-	if c.err != nil {
-		onError(c.err)
+func pCtxError(ctx context.Context) Error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrErr(ETimeout, context.Cause(ctx))
 	}
+	return ErrErr(ECancel, context.Cause(ctx))
 }
 
-func (c *Defer) Fold(onError func(Error)) {
-	c.Wait()
+// Unwrap
+// Wait for the task to complete and pass its failure to onError if any.
+// The EInternal error of a wait cycle is passed to onError as well, see SetWaitWatchdog.
+func (c Defer) Unwrap(onError func(Error)) {
+	if err := c.pAwait(); err != nil {
+		onError(err)
+	}
+}
+
+// pAwait wait for the task to complete, the error being its failure or the error of a wait cycle, see pWait
+func (c Defer) pAwait() Error {
+	s := c.pState()
+	if err := s.pWait(); err != nil {
+		return err
+	}
+	if s.err != nil {
+		return pStitchAwait(s.err)
+	}
+	return nil
+}
+
+func (c Defer) Fold(onError func(Error)) {
 	c.Unwrap(onError)
 }
 
-func (c *Defer) Get() {
-	c.Wait()
+// Get
+// Wait for the task to complete and throw its failure if any.
+// Throws the EInternal error of a wait cycle instead of deadlocking if detected, see SetWaitWatchdog.
+func (c Defer) Get() {
 	c.Unwrap(func(err Error) { Throw(err) })
 }
 
-func (c *Defer) Dump() Error {
-	c.Wait()
-	return c.err
+func (c Defer) Dump() Error {
+	s := c.pState()
+	if err := s.pWait(); err != nil {
+		return err
+	}
+	return s.err
 }
 
-func (c *DeferT[T]) Unwrap(onError func(Error)) T {
-	c.Defer.Unwrap(onError)
-	return c.val
+func (c DeferT[T]) Unwrap(onError func(Error)) (res T) {
+	if err := c.pAwait(); err != nil {
+		onError(err)
+		return
+	}
+	return c.pVal()
 }
 
-func (c *DeferT[T]) Fold(onError func(Error) T) T {
-	c.Wait()
-	if c.Defer.err != nil {
-		return onError(c.Defer.err)
+func (c DeferT[T]) Fold(onError func(Error) T) T {
+	if err := c.pAwait(); err != nil {
+		return onError(err)
 	}
-	return c.val
+	return c.pVal()
 }
 
-func (c *DeferT[T]) Get() T {
+func (c DeferT[T]) Get() T {
 	c.Defer.Get()
-	return c.val
+	return c.pVal()
 }
 
-func (c *DeferT[T]) Dump() (T, Error) {
-	c.Wait()
-	return c.val, c.Defer.err
+// GetCtx Equivalent of Get, but raises the error of WaitCtx if ctx ended before the task completed
+func (c DeferT[T]) GetCtx(ctx context.Context) T {
+	c.Defer.GetCtx(ctx)
+	return c.pVal()
 }
 
-func RunAsync(exec func()) (ret Defer) {
-	ret = UnsafeMakeDefer()
-	go ret.UnsafeApplyDefer(exec)
-	return
+func (c DeferT[T]) Dump() (T, Error) {
+	err := c.Defer.Dump()
+	return c.pVal(), err
 }
 
-func RunAsyncT[T any](exec func() T) (ret DeferT[T]) {
-	ret = UnsafeMakeDeferT[T]()
-	go ret.UnsafeApplyDefer(exec)
-	return
+// RunAsync Equivalent of Submit on the DefaultExecutor
+func RunAsync(exec func()) Defer {
+	return Submit(DefaultExecutor(), exec)
 }
 
-func UnsafeMakeDefer() (ret Defer) {
-	ret = Defer{err: nil, done: 0, m: sync.Mutex{}}
-	ret.m.Lock()
-	return
+// RunAsyncT Equivalent of SubmitT on the DefaultExecutor
+func RunAsyncT[T any](exec func() T) DeferT[T] {
+	return SubmitT(DefaultExecutor(), exec)
 }
 
-func (c *Defer) UnsafeApplyDefer(exec func()) {
-	defer func() {
-		o := recover()
-		if o != nil {
-			c.err = _AnyToError(o)
-		}
-		atomic.StoreUint32(&c.done, 1)
-		c.m.Unlock()
-	}()
-	exec()
+// RunAsyncCtx Equivalent of RunAsync, passing ctx to the task so that it can observe cancellation
+func RunAsyncCtx(ctx context.Context, exec func(ctx context.Context)) Defer {
+	return RunAsync(func() { exec(ctx) })
 }
 
-func UnsafeMakeDeferT[T any]() (ret DeferT[T]) {
-	ret = DeferT[T]{Defer: Defer{err: nil, done: 0, m: sync.Mutex{}}}
-	ret.Defer.m.Lock()
-	return
+// RunAsyncTCtx Equivalent of RunAsyncT, passing ctx to the task so that it can observe cancellation
+func RunAsyncTCtx[T any](ctx context.Context, exec func(ctx context.Context) T) DeferT[T] {
+	return RunAsyncT(func() T { return exec(ctx) })
 }
 
-func (c *DeferT[T]) UnsafeApplyDefer(exec func() T) {
-	defer func() {
-		o := recover()
-		if o != nil {
-			c.Defer.err = _AnyToError(o)
-		}
-		atomic.StoreUint32(&c.Defer.done, 1)
-		c.Defer.m.Unlock()
-	}()
-	c.val = exec()
+// pApply run exec and complete the Defer with its outcome
+func (c Defer) pApply(exec func()) {
+	c.pState().pRunning()
+	normal := false
+	defer func() { c.pComplete(pRecovered(recover(), normal)) }()
+	exec()
+	normal = true
 }
+
+// pApply run exec and complete the DeferT with its outcome
+func (c DeferT[T]) pApply(exec func() T) {
+	c.pState().pRunning()
+	var val T
+	normal := false
+	defer func() { c.pComplete(val, pRecovered(recover(), normal)) }()
+	val = exec()
+	normal = true
+}
+
+// UnsafeMakeDefer create a pending Defer, to be completed by exactly one call to UnsafeApplyDefer
+//
+// Deprecated: a Defer that is never applied blocks its waiters forever, use MakePromise instead.
+func UnsafeMakeDefer() Defer { return pMakeDefer() }
+
+// UnsafeApplyDefer run exec and complete the Defer with its outcome
+//
+// Deprecated: use MakePromise and Promise.Complete with the outcome of Run instead.
+func (c Defer) UnsafeApplyDefer(exec func()) { c.pApply(exec) }
+
+// UnsafeMakeDeferT create a pending DeferT, to be completed by exactly one call to UnsafeApplyDefer
+//
+// Deprecated: a DeferT that is never applied blocks its waiters forever, use MakePromise instead.
+func UnsafeMakeDeferT[T any]() DeferT[T] { return pMakeDeferT[T]() }
+
+// UnsafeApplyDefer run exec and complete the DeferT with its outcome
+//
+// Deprecated: use MakePromise and Promise.Complete with the outcome of RunT instead.
+func (c DeferT[T]) UnsafeApplyDefer(exec func() T) { c.pApply(exec) }
@@ -0,0 +1,107 @@
+package calm
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDeferCopiesShareCompletion(t *testing.T) {
+	gate := make(chan struct{})
+	d := RunAsyncT(func() int {
+		<-gate
+		return 42
+	})
+	holder := struct{ d DeferT[int] }{d: d}
+	ch := make(chan DeferT[int], 1)
+	ch <- d
+	plain := d.Defer
+	var wg sync.WaitGroup
+	results := make([]int, 8)
+	for i := range results {
+		i, c := i, d
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.Get()
+		}()
+	}
+	close(gate)
+	wg.Wait()
+	for i, v := range results {
+		if v != 42 {
+			t.Errorf("copy %d got %d", i, v)
+		}
+	}
+	if v, err := (<-ch).Dump(); (v != 42) || (err != nil) {
+		t.Errorf("sent copy got %d, %v", v, err)
+	}
+	if v := holder.d.Get(); v != 42 {
+		t.Errorf("stored copy got %d", v)
+	}
+	if err := plain.Dump(); err != nil {
+		t.Errorf("embedded Defer got %v", err)
+	}
+}
+
+func TestDeferCopiesShareFailure(t *testing.T) {
+	d := RunAsync(func() { ThrowClean(EResNone, "gone") })
+	copies := []Defer{d, d, d}
+	for i, c := range copies {
+		if err := c.Dump(); (err == nil) || (err.ECode() != EResNone) {
+			t.Errorf("copy %d got %v", i, err)
+		}
+	}
+}
+
+func TestZeroDefer(t *testing.T) {
+	var d Defer
+	d.Wait()
+	d.Get()
+	if err := d.Dump(); err != nil {
+		t.Errorf("zero Defer failed with %v", err)
+	}
+	select {
+	case <-d.Done():
+	default:
+		t.Error("zero Defer not done")
+	}
+	called := false
+	d.OnComplete(func(err Error) { called = err == nil })
+	if !called {
+		t.Error("completion callback of zero Defer not run")
+	}
+}
+
+func TestZeroDeferT(t *testing.T) {
+	var d DeferT[string]
+	if v := d.Get(); v != "" {
+		t.Errorf("zero DeferT got %q", v)
+	}
+	if v, err := d.Dump(); (v != "") || (err != nil) {
+		t.Errorf("zero DeferT got %q, %v", v, err)
+	}
+	if v := Then(d, func(r ResultT[string]) int { return len(r.Get()) + 1 }).Get(); v != 1 {
+		t.Errorf("continuation of zero DeferT got %d", v)
+	}
+}
+
+func TestDeferTFromDefer(t *testing.T) {
+	d := DeferT[int]{Defer: UnsafeMakeDefer()}
+	go d.UnsafeApplyDefer(func() int { return 42 })
+	if v, err := d.Dump(); (v != 0) || (err != nil) {
+		t.Errorf("got %d, %v, want the zero value", v, err)
+	}
+	failed := DeferT[int]{Defer: UnsafeMakeDefer()}
+	go failed.UnsafeApplyDefer(func() int { ThrowClean(EResNone, "gone"); return 1 })
+	if _, err := failed.Dump(); (err == nil) || (err.ECode() != EResNone) {
+		t.Errorf("got %v, want EResNone", err)
+	}
+}
+
+func TestDeferTReassigned(t *testing.T) {
+	d := RunAsyncT(func() int { return 1 })
+	d.Defer = RunAsync(func() {})
+	if v, err := d.Dump(); (v != 0) || (err != nil) {
+		t.Errorf("got %d, %v, want the zero value", v, err)
+	}
+}
@@ -2,7 +2,9 @@ package calm
 
 import (
 	"fmt"
+	"reflect"
 	"runtime"
+	"time"
 )
 
 type Error interface {
@@ -29,36 +31,165 @@ type ErrorInfo interface {
 	Meta() any
 }
 
+// ErrorNode
+// Optional interface for calm.Error implementations to take part in nesting, tracing and printing like built-in ones.
+// Info is the top level slice, Next the nested error (nil for a root error) and Trace the captured program counters
+// of the top level slice (nil if none). A calm.Error that does not implement ErrorNode is handled through Slices(),
+// without any stack trace.
+type ErrorNode interface {
+	Error
+	Info() ErrorInfo
+	Next() Error
+	Trace() []uintptr
+}
+
+// ErrorJoin
+// Optional extension of ErrorNode for errors nesting several causes, e.g. parallel failures.
+// Causes replaces Next when walking, tracing and printing the error.
+type ErrorJoin interface {
+	ErrorNode
+	Causes() []Error
+}
+
 type sErrNode struct {
-	info  ErrorInfo
-	trace []uintptr
+	info   ErrorInfo
+	trace  []uintptr
+	frames []StackFrame // symbolized frames of a remote error, in place of trace
 }
 
 func (e *sErrNode) TCode() uint32       { return e.info.TCode() }
 func (e *sErrNode) ECode() uint32       { return e.info.ECode() }
 func (e *sErrNode) Slices() []ErrorInfo { return []ErrorInfo{e.info} }
 func (e *sErrNode) Error() string       { return PrintDetails(e, FullPrint) }
+func (e *sErrNode) Info() ErrorInfo     { return e.info }
+func (e *sErrNode) Next() Error         { return nil }
+func (e *sErrNode) Trace() []uintptr    { return e.trace }
+
+func (e *sErrNode) pFrames() []StackFrame { return e.frames }
+
+// Unwrap expose the std error wrapped as the Meta of this slice, for errors.Is and errors.As
+func (e *sErrNode) Unwrap() []error { return pAppendMetaErr(nil, e.info) }
+
+// Is report if the top slice matches a sentinel created by CodeSentinel
+func (e *sErrNode) Is(target error) bool {
+	if s, ok := target.(sCodeSentinel); ok {
+		return s.fCode == MakeErrCode(e.TCode(), e.ECode())
+	}
+	return false
+}
 
 type sErrChain struct {
 	sErrNode
-	next any
-}
-
-func (e *sErrChain) Error() string { return PrintDetails(e, FullPrint) }
-
-func (e *sErrChain) Slices() []ErrorInfo {
-	var c any = e
-	var b []ErrorInfo
-	for {
-		switch n := c.(type) {
-		case *sErrChain:
-			b = append(b, n.info)
-			c = n.next
-		case *sErrNode:
-			b = append(b, n.info)
-			return b
+	next Error
+}
+
+func (e *sErrChain) Error() string       { return PrintDetails(e, FullPrint) }
+func (e *sErrChain) Next() Error         { return e.next }
+func (e *sErrChain) Slices() []ErrorInfo { return _ChainSlices(e) }
+
+// Unwrap expose the nested error and the std error wrapped as the Meta of this slice
+func (e *sErrChain) Unwrap() []error {
+	return pAppendMetaErr([]error{e.next}, e.info)
+}
+
+// _MaxNesting cap of the slices followed through Next, guarding against ErrorNode implementations forming a loop
+const _MaxNesting = 1024
+
+// _Unfold
+// Decompose e into the slices of its linear part top-down, and the causes nested below the last of them.
+// calm.Error implementations that are not ErrorNode are decomposed through Slices(), without any trace, as are the
+// nodes found past _MaxNesting slices. Built-in nodes found there are cut, their Slices() would unfold them again.
+// The chain is also cut when a custom node of pointer type is reached twice, only those can close a loop.
+func _Unfold(e Error) (slices []_Slice, causes []Error) {
+	var seen map[uintptr]bool
+	for e != nil {
+		if _, builtin := e.(interface{ pFrames() []StackFrame }); !builtin {
+			if v := reflect.ValueOf(e); v.Kind() == reflect.Pointer {
+				if seen == nil {
+					seen = map[uintptr]bool{}
+				}
+				if seen[v.Pointer()] {
+					return
+				}
+				seen[v.Pointer()] = true
+			}
+		}
+		if len(slices) >= _MaxNesting {
+			if _, builtin := e.(interface{ pFrames() []StackFrame }); !builtin {
+				for _, info := range pSlicesSafe(e) {
+					slices = append(slices, _Slice{info: info})
+				}
+			}
+			return
+		}
+		var info ErrorInfo
+		var trace []uintptr
+		var next Error
+		if node, ok := e.(ErrorNode); ok {
+			info, trace, next = pNodeSafe(node)
+		}
+		if info == nil {
+			for _, info := range pSlicesSafe(e) {
+				slices = append(slices, _Slice{info: info})
+			}
+			return
+		}
+		slice := _Slice{info: info, trace: trace}
+		if remote, ok := e.(interface{ pFrames() []StackFrame }); ok {
+			slice.frames = remote.pFrames()
 		}
+		slices = append(slices, slice)
+		if join, ok := e.(ErrorJoin); ok {
+			return slices, pCausesSafe(join)
+		}
+		e = next
 	}
+	return
+}
+
+func _ChainSlices(e Error) (b []ErrorInfo) {
+	Walk(e, func(_ int, info ErrorInfo) bool {
+		b = append(b, info)
+		return true
+	})
+	return
+}
+
+func pNodeSafe(node ErrorNode) (info ErrorInfo, trace []uintptr, next Error) {
+	defer func() {
+		if recover() != nil {
+			info, trace, next = nil, nil, nil
+		}
+	}()
+	return node.Info(), node.Trace(), node.Next()
+}
+
+func pCausesSafe(join ErrorJoin) (causes []Error) {
+	defer func() {
+		if recover() != nil {
+			causes = nil
+		}
+	}()
+	return join.Causes()
+}
+
+// pSlicesSafe never returns an empty list, unknown errors are represented by their codes
+func pSlicesSafe(e Error) (res []ErrorInfo) {
+	defer func() {
+		if recover() != nil || len(res) == 0 {
+			res = []ErrorInfo{pCodeOfSafe(e)}
+		}
+	}()
+	return e.Slices()
+}
+
+func pCodeOfSafe(e Error) (res ErrorInfo) {
+	defer func() {
+		if recover() != nil {
+			res = InfoCode(MakeErrCode(0, EInternal))
+		}
+	}()
+	return InfoCode(MakeErrCode(e.TCode(), e.ECode()))
 }
 
 // ErrNestByInfo
@@ -87,13 +218,15 @@ func ErrNestByInfo(nested Error, info ErrorInfo) Error {
 }
 
 func _ErrExtractNestPair(nested Error) (ErrorInfo, bool) {
-	switch t := nested.(type) {
-	case *sErrNode:
-		return t.info, t.trace != nil
-	case *sErrChain:
-		return t.info, t.trace != nil
+	if remote, ok := nested.(interface{ pFrames() []StackFrame }); ok && (remote.pFrames() != nil) {
+		return nested.(ErrorNode).Info(), true
 	}
-	return nil, false
+	if node, ok := nested.(ErrorNode); ok {
+		if info, trace, _ := pNodeSafe(node); info != nil {
+			return info, trace != nil
+		}
+	}
+	return pSlicesSafe(nested)[0], false
 }
 
 // ErrByInfo
@@ -106,9 +239,42 @@ func ErrByInfo(info ErrorInfo) Error {
 	if pErrOnRootSafe(err.TCode(), err) {
 		err.trace = pWithTrace()
 	}
+	pRecordRecent(err)
 	return err
 }
 
+// pErrRoot
+// Create a new calm.Error with its top slice set to info and a trace captured elsewhere, nesting next if not nil.
+// Like ErrByInfo, the corresponding OnErrRoot is called and the error is recorded, see RecordRecent, for failures
+// raised by the library itself such as recovered panics.
+func pErrRoot(info ErrorInfo, trace []uintptr, next Error) Error {
+	var err Error = &sErrNode{info: info, trace: trace}
+	if next != nil {
+		err = &sErrChain{sErrNode: sErrNode{info: info, trace: trace}, next: next}
+	}
+	pErrOnRootSafe(info.TCode(), err)
+	pRecordRecent(err)
+	return err
+}
+
+func pAppendMetaErr(list []error, info ErrorInfo) []error {
+	if err, ok := pMetaSafe(info).(error); ok && err != nil {
+		return append(list, err)
+	}
+	return list
+}
+
+func pMetaSafe(info ErrorInfo) (res any) {
+	defer func() { _ = recover() }()
+	return info.Meta()
+}
+
+// _TopInfo the top level slice of e
+func _TopInfo(e Error) ErrorInfo {
+	slices, _ := _Unfold(e)
+	return slices[0].info
+}
+
 func pWithTrace() (result []uintptr) {
 	result = make([]uintptr, 2048)
 	n := runtime.Callers(0, result)
@@ -149,6 +315,18 @@ func (e *sInfoCode) Clean() string  { return "" }
 // InfoCode construct a ErrorInfo that represents an error code
 func InfoCode(err uint64) ErrorInfo { return &sInfoCode{fCode: err} }
 
+type sCodeSentinel struct {
+	fCode uint64
+}
+
+func (e sCodeSentinel) Error() string {
+	return _PrintableErrorTag(&sInfoCode{fCode: e.fCode})
+}
+
+// CodeSentinel construct a comparable sentinel error matching any slice with the given error code.
+// e.g. errors.Is(err, calm.CodeSentinel(calm.EResNone)) reports if any slice of err carries EResNone
+func CodeSentinel(err uint64) error { return sCodeSentinel{fCode: err} }
+
 // ErrCodeN Equivalent of ErrNestByInfo(nested, InfoCode(err))
 func ErrCodeN(nested Error, err uint64) Error { return ErrNestByInfo(nested, InfoCode(err)) }
 
@@ -295,3 +473,38 @@ func ThrowStringerN(nested Error, err uint64, msg string, meta fmt.Stringer) {
 
 // ThrowStringer Equivalent of Throw(ErrDetail(err, msg, meta))
 func ThrowStringer(err uint64, msg string, meta fmt.Stringer) { Throw(ErrStringer(err, msg, meta)) }
+
+// RetryAfter retry delay suggested by the failing party, the Meta of slices created by InfoRetryAfter
+type RetryAfter time.Duration
+
+type sInfoRetry struct {
+	sInfoClean
+	after RetryAfter
+}
+
+func (e *sInfoRetry) Meta() any { return e.after }
+
+// InfoRetryAfter construct a ErrorInfo that represents an error code with a sanitized message and a retry delay
+func InfoRetryAfter(err uint64, msg string, after time.Duration) ErrorInfo {
+	return &sInfoRetry{sInfoClean: sInfoClean{sInfoCode: sInfoCode{fCode: err}, msg: msg}, after: RetryAfter(after)}
+}
+
+// ErrRetryAfterN Equivalent of ErrNestByInfo(nested, InfoRetryAfter(err, msg, after))
+func ErrRetryAfterN(nested Error, err uint64, msg string, after time.Duration) Error {
+	return ErrNestByInfo(nested, InfoRetryAfter(err, msg, after))
+}
+
+// ErrRetryAfter Equivalent of ErrByInfo(InfoRetryAfter(err, msg, after))
+func ErrRetryAfter(err uint64, msg string, after time.Duration) Error {
+	return ErrByInfo(InfoRetryAfter(err, msg, after))
+}
+
+// ThrowRetryAfterN Equivalent of Throw(ErrRetryAfterN(nested, err, msg, after))
+func ThrowRetryAfterN(nested Error, err uint64, msg string, after time.Duration) {
+	Throw(ErrRetryAfterN(nested, err, msg, after))
+}
+
+// ThrowRetryAfter Equivalent of Throw(ErrRetryAfter(err, msg, after))
+func ThrowRetryAfter(err uint64, msg string, after time.Duration) {
+	Throw(ErrRetryAfter(err, msg, after))
+}
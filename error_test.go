@@ -0,0 +1,61 @@
+package calm
+
+import (
+	"strings"
+	"testing"
+)
+
+// _LoopNode a custom ErrorNode whose Next may lead back to itself
+type _LoopNode struct {
+	info ErrorInfo
+	next Error
+}
+
+func (n *_LoopNode) TCode() uint32       { return n.info.TCode() }
+func (n *_LoopNode) ECode() uint32       { return n.info.ECode() }
+func (n *_LoopNode) Slices() []ErrorInfo { return []ErrorInfo{n.info} }
+func (n *_LoopNode) Error() string       { return n.info.Clean() }
+func (n *_LoopNode) Info() ErrorInfo     { return n.info }
+func (n *_LoopNode) Next() Error         { return n.next }
+func (n *_LoopNode) Trace() []uintptr    { return nil }
+
+// _ValueLoop a custom ErrorNode of value type that always nests a copy of itself
+type _ValueLoop struct{ n int }
+
+func (n _ValueLoop) TCode() uint32       { return 0 }
+func (n _ValueLoop) ECode() uint32       { return ERequest }
+func (n _ValueLoop) Slices() []ErrorInfo { return []ErrorInfo{n.Info()} }
+func (n _ValueLoop) Error() string       { return "loop" }
+func (n _ValueLoop) Info() ErrorInfo     { return InfoClean(ERequest, "loop") }
+func (n _ValueLoop) Next() Error         { return _ValueLoop{n: n.n + 1} }
+func (n _ValueLoop) Trace() []uintptr    { return nil }
+
+func TestUnfoldSelfLoop(t *testing.T) {
+	self := &_LoopNode{info: InfoClean(ERequest, "self")}
+	self.next = self
+	if slices := ErrCleanN(self, EInternal, "top").Slices(); len(slices) != 2 {
+		t.Errorf("%d slices, want 2", len(slices))
+	}
+	if text := PrintCleans(self); strings.Count(text, "self") != 1 {
+		t.Errorf("unexpected print %q", text)
+	}
+}
+
+func TestUnfoldLoopThroughBuiltin(t *testing.T) {
+	a := &_LoopNode{info: InfoClean(ERequest, "a")}
+	a.next = ErrCleanN(a, EResNone, "b")
+	if slices := a.Slices(); len(slices) != 1 {
+		t.Errorf("%d custom slices, want 1", len(slices))
+	}
+	if slices := ErrCleanN(a, EInternal, "top").Slices(); len(slices) != 3 {
+		t.Errorf("%d slices, want 3", len(slices))
+	}
+	_ = PrintDetails(a, FullPrint)
+}
+
+func TestUnfoldValueLoop(t *testing.T) {
+	if slices := ErrCleanN(_ValueLoop{}, EInternal, "top").Slices(); len(slices) != _MaxNesting+1 {
+		t.Errorf("%d slices, want %d", len(slices), _MaxNesting+1)
+	}
+	_ = PrintCleans(_ValueLoop{})
+}
@@ -0,0 +1,258 @@
+package calm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Executor
+// Runs tasks on behalf of Submit and RunAsync, e.g. on a bounded pool of goroutines.
+// Execute either schedules task to run exactly once, or returns an error without running it, e.g. EBacklog when full.
+type Executor interface {
+	Execute(task func()) Error
+}
+
+type sGoExecutor struct{}
+
+func (sGoExecutor) String() string { return "go" }
+
+func (sGoExecutor) Execute(task func()) Error {
+	go task()
+	return nil
+}
+
+// GoExecutor runs every task on a new goroutine, the default executor
+var GoExecutor Executor = sGoExecutor{}
+
+type sExecutorRef struct{ e Executor }
+
+var _DefaultExecutor atomic.Pointer[sExecutorRef]
+
+// SetDefaultExecutor set the executor used by RunAsync and RunAsyncT, nil restores GoExecutor
+func SetDefaultExecutor(e Executor) {
+	if e == nil {
+		e = GoExecutor
+	}
+	_DefaultExecutor.Store(&sExecutorRef{e: e})
+}
+
+// DefaultExecutor the executor used by RunAsync and RunAsyncT
+func DefaultExecutor() Executor {
+	if ref := _DefaultExecutor.Load(); ref != nil {
+		return ref.e
+	}
+	return GoExecutor
+}
+
+// Submit
+// Run exec on e, the returned Defer completes with its outcome.
+// If e rejects the task, the Defer completes with the error of e instead, without running exec.
+func Submit(e Executor, exec func()) (ret Defer) {
+	ret = pMakeDefer()
+	ret.pState().pSetExecutor(e)
+	if err := pExecuteSafe(e, func() { ret.pApply(exec) }); err != nil {
+		ret.pComplete(err)
+	}
+	return
+}
+
+// SubmitT Equivalent of Submit for a task producing a value
+func SubmitT[T any](e Executor, exec func() T) (ret DeferT[T]) {
+	ret = pMakeDeferT[T]()
+	ret.pState().pSetExecutor(e)
+	if err := pExecuteSafe(e, func() { ret.pApply(exec) }); err != nil {
+		var zero T
+		ret.pComplete(zero, err)
+	}
+	return
+}
+
+func pExecuteSafe(e Executor, task func()) (err Error) {
+	defer func() {
+		if o := recover(); o != nil {
+			err = pPanicToError(o)
+		}
+	}()
+	return e.Execute(task)
+}
+
+// PoolConfig configuration of a WorkerPool
+type PoolConfig struct {
+	// Workers number of goroutines running tasks, at least 1
+	Workers int
+	// QueueSize number of tasks waiting for a worker beyond the idle ones, further tasks are rejected with EBacklog
+	QueueSize int
+	// Priorities number of priority levels, at least 1. See WithPriority
+	Priorities int
+}
+
+// PoolStats point-in-time view of a WorkerPool
+type PoolStats struct {
+	Workers   int
+	Active    int // workers running a task
+	Queued    int // tasks waiting for a worker
+	Completed uint64
+	Rejected  uint64
+}
+
+// WorkerPool
+// Executor running tasks on a fixed number of goroutines, with a bounded queue of pending tasks.
+// Tasks submitted with a higher priority, see WithPriority, are started first. Tasks of the same priority are
+// started in submission order. Panics of tasks passed directly to Execute are dropped.
+type WorkerPool struct {
+	m         sync.Mutex
+	cond      sync.Cond
+	config    PoolConfig
+	queues    [][]func() // one FIFO per priority level
+	queued    int
+	active    int
+	closed    bool
+	completed uint64
+	rejected  uint64
+	workers   sync.WaitGroup
+}
+
+func NewWorkerPool(config PoolConfig) *WorkerPool {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.QueueSize < 0 {
+		config.QueueSize = 0
+	}
+	if config.Priorities < 1 {
+		config.Priorities = 1
+	}
+	p := &WorkerPool{config: config, queues: make([][]func(), config.Priorities)}
+	p.cond.L = &p.m
+	p.workers.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go p.pWorker()
+	}
+	return p
+}
+
+func (p *WorkerPool) String() string { return fmt.Sprintf("worker pool %p", p) }
+
+// Execute schedule task with the lowest priority
+func (p *WorkerPool) Execute(task func()) Error { return p.pExecute(0, task) }
+
+type sPriorityExecutor struct {
+	pool     *WorkerPool
+	priority int
+}
+
+func (e sPriorityExecutor) String() string { return fmt.Sprintf("%v priority %d", e.pool, e.priority) }
+
+func (e sPriorityExecutor) Execute(task func()) Error { return e.pool.pExecute(e.priority, task) }
+
+// WithPriority
+// A view of p scheduling tasks with the given priority, from 0 (lowest) to Priorities-1 (highest).
+// Out of range priorities are clamped.
+func WithPriority(p *WorkerPool, priority int) Executor {
+	if priority < 0 {
+		priority = 0
+	} else if priority >= p.config.Priorities {
+		priority = p.config.Priorities - 1
+	}
+	return sPriorityExecutor{pool: p, priority: priority}
+}
+
+func (p *WorkerPool) pExecute(priority int, task func()) Error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed {
+		p.rejected++
+		return ErrClean(ECancel, "executor shut down")
+	}
+	if p.queued >= p.config.QueueSize+(p.config.Workers-p.active) {
+		p.rejected++
+		return ErrClean(EBacklog, "executor queue full")
+	}
+	p.queues[priority] = append(p.queues[priority], task)
+	p.queued++
+	p.cond.Signal()
+	return nil
+}
+
+// Shutdown
+// Stop accepting tasks, and wait for the pending ones to complete or ctx to end, whichever happens first.
+// Returns an ETimeout or ECancel error if ctx ended first, the pending tasks keep running in this case.
+func (p *WorkerPool) Shutdown(ctx context.Context) Error {
+	p.m.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.m.Unlock()
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return pCtxError(ctx)
+	}
+}
+
+// Stats a point-in-time view of the pool
+func (p *WorkerPool) Stats() PoolStats {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return PoolStats{
+		Workers: p.config.Workers, Active: p.active, Queued: p.queued,
+		Completed: p.completed, Rejected: p.rejected,
+	}
+}
+
+func (p *WorkerPool) pWorker() {
+	exited := true
+	defer func() {
+		if exited {
+			go p.pWorker() // the task called runtime.Goexit, replace the worker
+		} else {
+			p.workers.Done()
+		}
+	}()
+	for {
+		task, ok := p.pNext()
+		if !ok {
+			exited = false
+			return
+		}
+		p.pRun(task)
+	}
+}
+
+// pNext wait for the next task, false once the pool is shut down and drained
+func (p *WorkerPool) pNext() (func(), bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for (p.queued == 0) && !p.closed {
+		p.cond.Wait()
+	}
+	for i := len(p.queues) - 1; i >= 0; i-- {
+		if queue := p.queues[i]; len(queue) > 0 {
+			task := queue[0]
+			queue[0] = nil
+			p.queues[i] = queue[1:]
+			p.queued--
+			p.active++
+			return task, true
+		}
+	}
+	return nil, false
+}
+
+func (p *WorkerPool) pRun(task func()) {
+	defer func() {
+		_ = recover()
+		p.m.Lock()
+		p.active--
+		p.completed++
+		p.m.Unlock()
+	}()
+	task()
+}
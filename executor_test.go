@@ -0,0 +1,154 @@
+package calm
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// _Block occupy a worker of p until the returned release is called
+func _Block(t *testing.T, p *WorkerPool) (release func()) {
+	t.Helper()
+	gate := make(chan struct{})
+	active := p.Stats().Active
+	if err := p.Execute(func() { <-gate }); err != nil {
+		t.Fatal(err)
+	}
+	for p.Stats().Active <= active {
+		runtime.Gosched()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { close(gate) }) }
+}
+
+func _Code(err Error) uint32 {
+	if err == nil {
+		return 0
+	}
+	return err.ECode()
+}
+
+func TestPoolBacklog(t *testing.T) {
+	p := NewWorkerPool(PoolConfig{Workers: 2, QueueSize: 0})
+	gate := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		if err := p.Execute(func() { <-gate }); err != nil {
+			t.Fatalf("task %d rejected with idle workers: %v", i, err)
+		}
+	}
+	if code := _Code(p.Execute(func() {})); code != EBacklog {
+		t.Fatalf("code %d, want EBacklog", code)
+	}
+	close(gate)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := p.Stats(); (stats.Completed != 2) || (stats.Rejected != 1) {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestPoolQueueSize(t *testing.T) {
+	p := NewWorkerPool(PoolConfig{Workers: 1, QueueSize: 2})
+	release := _Block(t, p)
+	defer release()
+	for i := 0; i < 2; i++ {
+		if err := p.Execute(func() {}); err != nil {
+			t.Fatalf("task %d rejected: %v", i, err)
+		}
+	}
+	if code := _Code(p.Execute(func() {})); code != EBacklog {
+		t.Fatalf("code %d, want EBacklog", code)
+	}
+	if stats := p.Stats(); (stats.Active != 1) || (stats.Queued != 2) || (stats.Rejected != 1) {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestPoolPriority(t *testing.T) {
+	p := NewWorkerPool(PoolConfig{Workers: 1, QueueSize: 4, Priorities: 3})
+	release := _Block(t, p)
+	var m sync.Mutex
+	var order []int
+	for _, priority := range []int{0, 2, 1, 2} {
+		priority := priority
+		err := WithPriority(p, priority).Execute(func() {
+			m.Lock()
+			order = append(order, priority)
+			m.Unlock()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	release()
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{2, 2, 1, 0}
+	for i := range want {
+		if (len(order) != len(want)) || (order[i] != want[i]) {
+			t.Fatalf("order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestPoolShutdownDrains(t *testing.T) {
+	p := NewWorkerPool(PoolConfig{Workers: 2, QueueSize: 8})
+	var m sync.Mutex
+	ran := 0
+	for i := 0; i < 8; i++ {
+		_ = p.Execute(func() {
+			time.Sleep(time.Millisecond)
+			m.Lock()
+			ran++
+			m.Unlock()
+		})
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if ran != 8 {
+		t.Errorf("%d tasks ran before Shutdown returned, want 8", ran)
+	}
+	if code := _Code(p.Execute(func() {})); code != ECancel {
+		t.Errorf("code %d after shutdown, want ECancel", code)
+	}
+	if stats := p.Stats(); (stats.Completed != 8) || (stats.Rejected != 1) {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestPoolShutdownTimeout(t *testing.T) {
+	p := NewWorkerPool(PoolConfig{Workers: 1})
+	release := _Block(t, p)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if code := _Code(p.Shutdown(ctx)); code != ETimeout {
+		t.Fatalf("code %d, want ETimeout", code)
+	}
+	release()
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolGoexitReplacesWorker(t *testing.T) {
+	p := NewWorkerPool(PoolConfig{Workers: 1})
+	if err := Submit(p, runtime.Goexit).Dump(); _Code(err) != ECancel {
+		t.Fatalf("Goexit reported as %v, want ECancel", err)
+	}
+	if err := Submit(p, func() {}).Dump(); err != nil {
+		t.Fatalf("no worker left: %v", err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := p.Stats(); (stats.Workers != 1) || (stats.Active != 0) || (stats.Completed != 2) {
+		t.Errorf("stats %+v", stats)
+	}
+}
@@ -0,0 +1,166 @@
+package calm
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// All
+// Complete with the values of all ds, in order, once every one of them succeeded.
+// Fails with the first error in completion order without waiting for the remaining tasks.
+func All[T any](ds ...DeferT[T]) DeferT[[]T] {
+	ret := pMakeDeferT[[]T]()
+	vals := make([]T, len(ds))
+	if len(ds) == 0 {
+		ret.pComplete(vals, nil)
+		return ret
+	}
+	left := atomic.Int32{}
+	left.Store(int32(len(ds)))
+	for i, d := range ds {
+		i, d := i, d
+		d.pOnDone(func() {
+			v, err := d.Dump()
+			if err != nil {
+				ret.pComplete(nil, err)
+				return
+			}
+			vals[i] = v
+			if left.Add(-1) == 0 {
+				ret.pComplete(vals, nil)
+			}
+		})
+	}
+	return ret
+}
+
+// AllSettled Complete with the outcome of all ds, in order, once every one of them completed
+func AllSettled[T any](ds ...DeferT[T]) DeferT[[]ResultT[T]] {
+	ret := pMakeDeferT[[]ResultT[T]]()
+	results := make([]ResultT[T], len(ds))
+	if len(ds) == 0 {
+		ret.pComplete(results, nil)
+		return ret
+	}
+	left := atomic.Int32{}
+	left.Store(int32(len(ds)))
+	for i, d := range ds {
+		i, d := i, d
+		d.pOnDone(func() {
+			v, err := d.Dump()
+			results[i] = ResultT[T]{val: v, Result: Result{err: err}}
+			if left.Add(-1) == 0 {
+				ret.pComplete(results, nil)
+			}
+		})
+	}
+	return ret
+}
+
+// Any
+// Complete with the value of the first of ds to succeed.
+// If all of them fail, every failure is nested as a cause of the returned error in the order of ds, see Join. The
+// returned error has the code shared by the failures, EInternal if they differ.
+// Fails with ERequest if ds is empty.
+func Any[T any](ds ...DeferT[T]) DeferT[T] {
+	if len(ds) == 0 {
+		return ErrDeferT[T](_ErrNoTask())
+	}
+	ret := pMakeDeferT[T]()
+	errs := make([]Error, len(ds))
+	left := atomic.Int32{}
+	left.Store(int32(len(ds)))
+	for i, d := range ds {
+		i, d := i, d
+		d.pOnDone(func() {
+			v, err := d.Dump()
+			if err == nil {
+				ret.pComplete(v, nil)
+				return
+			}
+			errs[i] = err
+			if left.Add(-1) == 0 {
+				info := InfoClean(_JoinCode(errs), fmt.Sprintf("all %d tasks failed", len(ds)))
+				ret.pComplete(v, ErrJoinByInfo(info, errs...))
+			}
+		})
+	}
+	return ret
+}
+
+// Race
+// Complete with the outcome of the first of ds to complete, success or failure.
+// Fails with ERequest if ds is empty.
+func Race[T any](ds ...DeferT[T]) DeferT[T] {
+	if len(ds) == 0 {
+		return ErrDeferT[T](_ErrNoTask())
+	}
+	ret := pMakeDeferT[T]()
+	for _, d := range ds {
+		d := d
+		d.pOnDone(func() { ret.pComplete(d.Dump()) })
+	}
+	return ret
+}
+
+func _ErrNoTask() Error { return ErrClean(ERequest, "no task to wait for") }
+
+// Then
+// Continue with next once d completes, success or failure.
+// next can raise errors by Throw, its outcome completes the returned DeferT.
+func Then[T any, U any](d DeferT[T], next func(ResultT[T]) U) DeferT[U] {
+	ret := pMakeDeferT[U]()
+	d.pOnDone(func() {
+		v, err := d.Dump()
+		ret.pForward(SubmitT(DefaultExecutor(), func() U { return next(ResultT[T]{val: v, Result: Result{err: err}}) }))
+	})
+	return ret
+}
+
+// MapAsync
+// Continue with f on the value of d once it succeeds.
+// If d fails, its error is propagated as is and f is never called.
+func MapAsync[T any, U any](d DeferT[T], f func(T) U) DeferT[U] {
+	ret := pMakeDeferT[U]()
+	d.pOnDone(func() {
+		v, err := d.Dump()
+		if err != nil {
+			var zero U
+			ret.pComplete(zero, err)
+			return
+		}
+		ret.pForward(SubmitT(DefaultExecutor(), func() U { return f(v) }))
+	})
+	return ret
+}
+
+// FlatMapAsync
+// Continue with the task started by f on the value of d once it succeeds.
+// If d fails, its error is propagated as is and f is never called.
+func FlatMapAsync[T any, U any](d DeferT[T], f func(T) DeferT[U]) DeferT[U] {
+	ret := pMakeDeferT[U]()
+	d.pOnDone(func() {
+		v, err := d.Dump()
+		if err != nil {
+			var zero U
+			ret.pComplete(zero, err)
+			return
+		}
+		started := SubmitT(DefaultExecutor(), func() DeferT[U] { return f(v) })
+		started.pOnDone(func() {
+			next, err := started.Dump()
+			if err != nil {
+				var zero U
+				ret.pComplete(zero, err)
+				return
+			}
+			ret.pForward(next)
+		})
+	})
+	return ret
+}
+
+// pForward complete c with the outcome of d once it completes
+func (c DeferT[T]) pForward(d DeferT[T]) {
+	d.pOnDone(func() { c.pComplete(d.Dump()) })
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	nethttp "net/http"
+	"strconv"
+	"time"
+
+	"github.com/DWVoid/calm"
+)
+
+// ContentTypeWire media type of a response body holding an error encoded by calm.EncodeWire
+const ContentTypeWire = "application/vnd.calm.error"
+
+// _MaxErrorBody cap of the error response bodies read for decoding
+const _MaxErrorBody = 64 << 10
+
+// Transport
+// An http.RoundTripper turning failures into calm errors, see TransportError and ResponseError.
+// Unlike a plain http.RoundTripper, error statuses are reported as an error with no response, the body being
+// consumed and closed. net/http.Client wraps the errors in a *url.Error, use errors.As to retrieve the calm.Error.
+type Transport struct {
+	// Base the transport performing the requests, net/http.DefaultTransport if nil
+	Base nethttp.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *nethttp.Request) (*nethttp.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = nethttp.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, TransportError(err)
+	}
+	if err := ResponseError(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// TransportError
+// Map a failure to obtain a response with calm.ClassifyCode: cancellation to ECancel, deadlines to ETimeout,
+// name resolution failures to ENetNone, refused, reset or failed connections to ENetDown, other timeouts to ENetLost,
+// and anything not recognised to ENetFail. The original error is nested in the result.
+func TransportError(err error) calm.Error {
+	var typed calm.Error
+	if errors.As(err, &typed) {
+		return typed
+	}
+	code, ok := calm.ClassifyCode(err)
+	if !ok {
+		code = calm.ENetFail
+	}
+	return calm.ErrErr(code, err)
+}
+
+// ResponseError
+// Map an error status of resp, nil for statuses below 400. 404 maps to EResNone, 401 and 403 to EResAuth,
+// 410 to EResGone, 408 and 504 to ETimeout, 429 and 503 to EResRetry with the Retry-After delay as calm.RetryAfter
+// meta, other 4xx to ERequest and other 5xx to EResFail. Problem details and calm.EncodeWire bodies are decoded
+// into a remote error nested in the result. The body of an error response is consumed and closed.
+func ResponseError(resp *nethttp.Response) calm.Error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, _MaxErrorBody))
+	msg, remote := _DecodeBody(resp.Header.Get("Content-Type"), body)
+	if msg == "" {
+		msg = nethttp.StatusText(resp.StatusCode)
+	}
+	code := _StatusCode(resp.StatusCode)
+	info := calm.InfoClean(code, msg)
+	if code == calm.EResRetry {
+		if after, ok := _RetryAfter(resp.Header.Get("Retry-After")); ok {
+			info = calm.InfoRetryAfter(code, msg, after)
+		}
+	}
+	if remote != nil {
+		return calm.ErrNestByInfo(remote, info)
+	}
+	return calm.ErrByInfo(info)
+}
+
+func _StatusCode(status int) uint64 {
+	switch status {
+	case nethttp.StatusNotFound:
+		return calm.EResNone
+	case nethttp.StatusUnauthorized, nethttp.StatusForbidden:
+		return calm.EResAuth
+	case nethttp.StatusGone:
+		return calm.EResGone
+	case nethttp.StatusRequestTimeout, nethttp.StatusGatewayTimeout:
+		return calm.ETimeout
+	case nethttp.StatusTooManyRequests, nethttp.StatusServiceUnavailable:
+		return calm.EResRetry
+	}
+	if status < 500 {
+		return calm.ERequest
+	}
+	return calm.EResFail
+}
+
+// _DecodeBody the message and remote error carried by an error body, if any
+func _DecodeBody(contentType string, body []byte) (string, calm.Error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case ContentTypeProblem:
+		var problem Problem
+		if json.Unmarshal(body, &problem) != nil {
+			return "", nil
+		}
+		if len(problem.Errors) > 0 {
+			if remote, err := calm.DecodeJSON(problem.Errors); err == nil {
+				return problem.Detail, remote
+			}
+		}
+		return problem.Detail, nil
+	case ContentTypeWire:
+		if remote, err := calm.DecodeWire(body); err == nil {
+			return "", remote
+		}
+	}
+	return "", nil
+}
+
+// _RetryAfter parse a Retry-After header, in seconds or as an HTTP date
+func _RetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := nethttp.ParseTime(value); err == nil {
+		if after := time.Until(at); after > 0 {
+			return after, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DWVoid/calm"
+)
+
+func _Get(t *testing.T, ctx context.Context, url string) calm.Error {
+	t.Helper()
+	req, err := nethttp.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&nethttp.Client{Transport: &Transport{}}).Do(req)
+	if err == nil {
+		_ = resp.Body.Close()
+		return nil
+	}
+	var typed calm.Error
+	if !errors.As(err, &typed) {
+		t.Fatalf("not a calm.Error: %v", err)
+	}
+	return typed
+}
+
+func TestResponseStatusMapping(t *testing.T) {
+	for _, c := range []struct {
+		status int
+		code   uint32
+	}{
+		{nethttp.StatusNotFound, calm.EResNone},
+		{nethttp.StatusUnauthorized, calm.EResAuth},
+		{nethttp.StatusForbidden, calm.EResAuth},
+		{nethttp.StatusGone, calm.EResGone},
+		{nethttp.StatusTooManyRequests, calm.EResRetry},
+		{nethttp.StatusServiceUnavailable, calm.EResRetry},
+		{nethttp.StatusBadRequest, calm.ERequest},
+		{nethttp.StatusInternalServerError, calm.EResFail},
+	} {
+		status := c.status
+		server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(status)
+		}))
+		err := _Get(t, context.Background(), server.URL)
+		server.Close()
+		if err == nil {
+			t.Fatalf("%d: no error", status)
+		}
+		if (err.TCode() != 0) || (err.ECode() != c.code) {
+			t.Errorf("%d: code %d:%d, want 0:%d", status, err.TCode(), err.ECode(), c.code)
+		}
+		after, found := calm.FindMeta[calm.RetryAfter](err)
+		if c.code == calm.EResRetry {
+			if !found || (time.Duration(after) != 7*time.Second) {
+				t.Errorf("%d: retry after %v %v, want 7s", status, time.Duration(after), found)
+			}
+		} else if found {
+			t.Errorf("%d: unexpected retry after", status)
+		}
+	}
+}
+
+func TestResponseProblemBody(t *testing.T) {
+	ExposeChain = true
+	defer func() { ExposeChain = false }()
+	server := httptest.NewServer(Middleware(nethttp.HandlerFunc(func(nethttp.ResponseWriter, *nethttp.Request) {
+		calm.ThrowCleanN(calm.ErrClean(calm.EStgNone, "no such row"), calm.EResNone, "user not found")
+	})))
+	defer server.Close()
+	err := _Get(t, context.Background(), server.URL)
+	slices := err.Slices()
+	if len(slices) != 3 {
+		t.Fatalf("want the status slice and 2 remote slices, got %d: %v", len(slices), err)
+	}
+	if (slices[0].ECode() != calm.EResNone) || (slices[0].Clean() != "user not found") || calm.IsRemote(slices[0]) {
+		t.Errorf("unexpected status slice %v", err)
+	}
+	for i, code := range []uint32{calm.EResNone, calm.EStgNone} {
+		if info := slices[i+1]; !calm.IsRemote(info) || (info.ECode() != code) {
+			t.Errorf("slice %d: want remote code %d, got %v", i+1, code, err)
+		}
+	}
+	if slices[2].Clean() != "no such row" {
+		t.Errorf("nested message %q", slices[2].Clean())
+	}
+}
+
+func TestResponseWireBody(t *testing.T) {
+	join := calm.Join(calm.ErrClean(calm.ETimeout, "db"), calm.ErrClean(calm.ECancel, "cache"))
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", ContentTypeWire)
+		w.WriteHeader(nethttp.StatusBadGateway)
+		_, _ = w.Write(calm.EncodeWire(join, nil))
+	}))
+	defer server.Close()
+	err := _Get(t, context.Background(), server.URL)
+	if err.ECode() != calm.EResFail {
+		t.Fatalf("code %d, want EResFail", err.ECode())
+	}
+	var codes []uint32
+	calm.Walk(err, func(depth int, info calm.ErrorInfo) bool {
+		if (depth > 1) && calm.IsRemote(info) {
+			codes = append(codes, info.ECode())
+		}
+		return true
+	})
+	if (len(codes) != 2) || (codes[0] != calm.ETimeout) || (codes[1] != calm.ECancel) {
+		t.Errorf("remote causes %v, want [%d %d]", codes, calm.ETimeout, calm.ECancel)
+	}
+}
+
+func TestTransportRefused(t *testing.T) {
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	err := _Get(t, context.Background(), "http://"+addr)
+	if err.ECode() != calm.ENetDown {
+		t.Errorf("code %d, want ENetDown: %v", err.ECode(), err)
+	}
+}
+
+func TestTransportDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := _Get(t, ctx, server.URL)
+	if err.ECode() != calm.ETimeout {
+		t.Errorf("code %d, want ETimeout: %v", err.ECode(), err)
+	}
+}
+
+func TestMiddlewareRoundTrip(t *testing.T) {
+	server := httptest.NewServer(Middleware(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path == "/ok" {
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		calm.ThrowClean(calm.EBacklog, "too busy")
+	})))
+	defer server.Close()
+	if err := _Get(t, context.Background(), server.URL+"/ok"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err := _Get(t, context.Background(), server.URL+"/busy")
+	// EBacklog is served as 429, mapped back to EResRetry on the client
+	if err.ECode() != calm.EResRetry {
+		t.Fatalf("code %d, want EResRetry: %v", err.ECode(), err)
+	}
+	slices := err.Slices()
+	if (len(slices) != 2) || (slices[1].ECode() != calm.EBacklog) || (slices[1].Clean() != "too busy") {
+		t.Errorf("unexpected remote error %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package http translates between calm errors and HTTP, on the server and on the client side
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+
+	"github.com/DWVoid/calm"
+)
+
+// ContentTypeProblem media type of RFC 7807 problem details
+const ContentTypeProblem = "application/problem+json"
+
+// StatusMapper
+// Optional interface of a registered calm.IErrType supplying the HTTP status of its error codes.
+// Returning 0 falls back to 500 Internal Server Error.
+type StatusMapper interface {
+	HTTPStatus(err uint32) int
+}
+
+// Problem
+// RFC 7807 problem details. Errors is an extension member holding the top slice of the calm.Error in the format of
+// calm.EncodeJSON, or the whole error if ExposeChain is set. It only ever carries codes, names and clean messages.
+type Problem struct {
+	Type     string          `json:"type"`
+	Title    string          `json:"title"`
+	Status   int             `json:"status"`
+	Detail   string          `json:"detail,omitempty"`
+	Instance string          `json:"instance,omitempty"`
+	Errors   json.RawMessage `json:"errors,omitempty"`
+}
+
+// ExposeChain
+// Include every slice of the error in Problem.Errors, encoded by calm.EncodeJSON with calm.CleanEncode, instead of
+// the top slice only. The clean messages of nested slices often name internal resources, only set it for trusted
+// clients. Set it before serving requests.
+var ExposeChain bool
+
+var _SysStatus = map[uint32]int{
+	calm.EConfig:   nethttp.StatusInternalServerError,
+	calm.ERequest:  nethttp.StatusBadRequest,
+	calm.EDenied:   nethttp.StatusForbidden,
+	calm.EInternal: nethttp.StatusInternalServerError,
+
+	calm.EStgNone:  nethttp.StatusNotFound,
+	calm.EStgFull:  nethttp.StatusInsufficientStorage,
+	calm.EStgQueue: nethttp.StatusServiceUnavailable,
+	calm.EStgLost:  nethttp.StatusGatewayTimeout,
+	calm.EStgFail:  nethttp.StatusInternalServerError,
+
+	calm.ENetNone:     nethttp.StatusBadGateway,
+	calm.ENetEarly:    nethttp.StatusServiceUnavailable,
+	calm.ENetDown:     nethttp.StatusServiceUnavailable,
+	calm.ENetQueue:    nethttp.StatusServiceUnavailable,
+	calm.ENetRetry:    nethttp.StatusServiceUnavailable,
+	calm.ENetMaxRetry: nethttp.StatusBadGateway,
+	calm.ENetLost:     nethttp.StatusGatewayTimeout,
+	calm.ENetFail:     nethttp.StatusBadGateway,
+
+	calm.EResNone:  nethttp.StatusNotFound,
+	calm.EResAuth:  nethttp.StatusUnauthorized,
+	calm.EResRetry: nethttp.StatusServiceUnavailable,
+	calm.EResGone:  nethttp.StatusGone,
+	calm.EResFail:  nethttp.StatusBadGateway,
+
+	calm.ETimeout: nethttp.StatusGatewayTimeout,
+	calm.ECancel:  nethttp.StatusServiceUnavailable,
+	calm.EBacklog: nethttp.StatusTooManyRequests,
+}
+
+// StatusOf
+// The HTTP status for the top slice of err: from the system code table for system errors, from the registered
+// calm.IErrType if it implements StatusMapper, 500 Internal Server Error otherwise.
+func StatusOf(err calm.Error) int {
+	tCode, eCode := err.TCode(), err.ECode()
+	if tCode == 0 {
+		if status, ok := _SysStatus[eCode]; ok {
+			return status
+		}
+	} else if mapper, ok := calm.ErrType(tCode).(StatusMapper); ok {
+		if status := pStatusSafe(mapper, eCode); status != 0 {
+			return status
+		}
+	}
+	return nethttp.StatusInternalServerError
+}
+
+func pStatusSafe(mapper StatusMapper, eCode uint32) (res int) {
+	defer func() { _ = recover() }()
+	return mapper.HTTPStatus(eCode)
+}
+
+// ProblemOf
+// The problem details for err, with the clean message of its top slice as detail and the request path as instance.
+// Detail messages of err are never included.
+func ProblemOf(r *nethttp.Request, err calm.Error) *Problem {
+	status := StatusOf(err)
+	problem := &Problem{Type: "about:blank", Title: nethttp.StatusText(status), Status: status}
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+	encoded, e := calm.EncodeJSON(err, calm.CleanEncode)
+	if e != nil {
+		return problem
+	}
+	var top struct{ Name, Clean string }
+	if json.Unmarshal(encoded, &top) == nil {
+		if problem.Detail = top.Clean; problem.Detail == "" {
+			problem.Detail = top.Name
+		}
+	}
+	if ExposeChain {
+		problem.Errors = encoded
+	} else {
+		problem.Errors = _TopSlice(encoded)
+	}
+	return problem
+}
+
+// _TopSlice the top slice of an error encoded by calm.EncodeJSON, with the nested slices cut
+func _TopSlice(encoded []byte) json.RawMessage {
+	var top map[string]json.RawMessage
+	if json.Unmarshal(encoded, &top) != nil {
+		return nil
+	}
+	delete(top, "next")
+	delete(top, "causes")
+	res, _ := json.Marshal(top)
+	return res
+}
+
+// WriteProblem write the problem details for err as the response
+func WriteProblem(w nethttp.ResponseWriter, r *nethttp.Request, err calm.Error) {
+	problem := ProblemOf(r, err)
+	body, e := json.Marshal(problem)
+	if e != nil {
+		body = []byte(fmt.Sprintf(`{"type":"about:blank","title":%q,"status":%d}`, problem.Title, problem.Status))
+	}
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DWVoid/calm"
+)
+
+func _NestedFailure() calm.Error {
+	inner := calm.ErrClean(calm.EStgNone, "row missing in users.db for token s3cr3t")
+	return calm.ErrCleanN(inner, calm.EResNone, "user not found")
+}
+
+func _Serve(t *testing.T, err calm.Error) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := Middleware(nethttp.HandlerFunc(func(nethttp.ResponseWriter, *nethttp.Request) { calm.Throw(err) }))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/users/42", nil))
+	return w
+}
+
+func TestProblemTopSliceOnly(t *testing.T) {
+	w := _Serve(t, _NestedFailure())
+	if w.Code != nethttp.StatusNotFound {
+		t.Fatalf("status %d, want 404", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeProblem {
+		t.Fatalf("content type %q", ct)
+	}
+	body := w.Body.String()
+	for _, leak := range []string{"s3cr3t", "users.db", "next", "causes"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("body leaks %q: %s", leak, body)
+		}
+	}
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if (problem.Detail != "user not found") || (problem.Instance != "/users/42") {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	remote, err := calm.DecodeJSON(problem.Errors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (remote.ECode() != calm.EResNone) || (len(remote.Slices()) != 1) {
+		t.Errorf("unexpected errors member %s", problem.Errors)
+	}
+}
+
+func TestProblemExposeChain(t *testing.T) {
+	ExposeChain = true
+	defer func() { ExposeChain = false }()
+	w := _Serve(t, _NestedFailure())
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	remote, err := calm.DecodeJSON(problem.Errors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices := remote.Slices(); (len(slices) != 2) || (slices[1].ECode() != calm.EStgNone) {
+		t.Errorf("nested slice missing from %s", problem.Errors)
+	}
+}
+
+func TestProblemRemoteName(t *testing.T) {
+	remote, err := calm.DecodeJSON([]byte(`{"type":0,"code":128,"name":"upstream: gone"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	problem := ProblemOf(nil, remote)
+	var top map[string]any
+	if err = json.Unmarshal(problem.Errors, &top); err != nil {
+		t.Fatal(err)
+	}
+	if (problem.Detail != "upstream: gone") || (top["name"] != "upstream: gone") {
+		t.Errorf("local name used for a remote error: %+v %s", problem, problem.Errors)
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	server := httptest.NewServer(Middleware(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		hijacker, ok := w.(nethttp.Hijacker)
+		if !ok {
+			t.Error("writer is not a Hijacker")
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		_ = buf.Flush()
+	})))
+	defer server.Close()
+	resp, err := nethttp.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if (resp.StatusCode != nethttp.StatusOK) || (string(body) != "hijacked") {
+		t.Errorf("status %d body %q, want the hijacked response", resp.StatusCode, body)
+	}
+}
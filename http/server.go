@@ -0,0 +1,91 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	nethttp "net/http"
+
+	"github.com/DWVoid/calm"
+)
+
+// OnError
+// Optional hook called with every error turned into a response, e.g. to log its details which are never sent.
+// It is also called for errors raised after the response was started, which can no longer be reported to the client.
+var OnError func(r *nethttp.Request, err calm.Error)
+
+// HandlerFunc
+// An HTTP handler reporting failures by returning an error, or by raising one.
+// It is served under calm.Run semantics, failures are turned into problem details as by Middleware.
+type HandlerFunc func(w nethttp.ResponseWriter, r *nethttp.Request) error
+
+func (f HandlerFunc) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	tw := &_Writer{ResponseWriter: w}
+	err := calm.Run(func() {
+		if err := f(tw, r); err != nil {
+			calm.Throw(calm.ErrResult(err).Dump())
+		}
+	}).Dump()
+	_Fail(tw, r, err)
+}
+
+// Middleware
+// Serve next under calm.Run semantics, turning errors it raises into RFC 7807 problem details, see WriteProblem.
+// A panic with net/http.ErrAbortHandler is propagated to abort the response as net/http expects.
+func Middleware(next nethttp.Handler) nethttp.Handler {
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		tw := &_Writer{ResponseWriter: w}
+		_Fail(tw, r, calm.Run(func() { next.ServeHTTP(tw, r) }).Dump())
+	})
+}
+
+func _Fail(w *_Writer, r *nethttp.Request, err calm.Error) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, nethttp.ErrAbortHandler) {
+		panic(nethttp.ErrAbortHandler)
+	}
+	if OnError != nil {
+		OnError(r, err)
+	}
+	if !w.started {
+		WriteProblem(w, r, err)
+	}
+}
+
+// _Writer tracks if the response was started, the problem details can only be written before that
+type _Writer struct {
+	nethttp.ResponseWriter
+	started bool
+}
+
+func (w *_Writer) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *_Writer) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *_Writer) Flush() {
+	if f, ok := w.ResponseWriter.(nethttp.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
+// Hijack take over the connection, the response then counts as started as failures can no longer be reported
+func (w *_Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(nethttp.Hijacker)
+	if !ok {
+		return nil, nil, nethttp.ErrNotSupported
+	}
+	w.started = true
+	return h.Hijack()
+}
+
+// Unwrap expose the original writer to net/http.ResponseController
+func (w *_Writer) Unwrap() nethttp.ResponseWriter { return w.ResponseWriter }
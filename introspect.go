@@ -0,0 +1,147 @@
+package calm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	_TrackPending atomic.Bool
+	_PendingM     sync.Mutex
+	_Pending      = map[*sDefer]struct{}{}
+)
+
+// TrackPending
+// Enable or disable the registry of in-flight Defers, disabled by default as it captures a stack for every Defer
+// created. Defers created while enabled are listed by PendingDefers until they complete.
+func TrackPending(enabled bool) { _TrackPending.Store(enabled) }
+
+// PendingDefer point-in-time view of an in-flight Defer
+type PendingDefer struct {
+	Label    string        `json:"label,omitempty"`
+	Executor string        `json:"executor,omitempty"`
+	Created  time.Time     `json:"created"`
+	Age      time.Duration `json:"age"`
+	Stack    []StackFrame  `json:"stack"`
+}
+
+// pRegisterPending add a new Defer to the registry of in-flight Defers
+func (s *sDefer) pRegisterPending() {
+	s.since = time.Now()
+	s.pending = true
+	_PendingM.Lock()
+	_Pending[s] = struct{}{}
+	_PendingM.Unlock()
+}
+
+func (s *sDefer) pUnregisterPending() {
+	if s.pending {
+		_PendingM.Lock()
+		delete(_Pending, s)
+		_PendingM.Unlock()
+	}
+}
+
+// SetLabel label the Defer in the registry of in-flight Defers and in stuck wait reports, see TrackPending and
+// SetWaitWatchdog. The zero Defer cannot be labelled
+func (c Defer) SetLabel(label string) {
+	s := c.pState()
+	if s != _DeferNil {
+		s.m.Lock()
+		s.label = label
+		s.m.Unlock()
+	}
+}
+
+func (s *sDefer) pSetExecutor(e Executor) {
+	if !s.pending {
+		return
+	}
+	name := ""
+	if named, ok := e.(fmt.Stringer); ok {
+		name = named.String()
+	} else {
+		name = fmt.Sprintf("%T", e)
+	}
+	s.m.Lock()
+	s.executor = name
+	s.m.Unlock()
+}
+
+// PendingDefers the Defers created while TrackPending was enabled that have not completed yet, oldest first
+func PendingDefers() []PendingDefer {
+	_PendingM.Lock()
+	states := make([]*sDefer, 0, len(_Pending))
+	for s := range _Pending {
+		states = append(states, s)
+	}
+	_PendingM.Unlock()
+	now := time.Now()
+	result := make([]PendingDefer, len(states))
+	for i, s := range states {
+		s.m.Lock()
+		result[i] = PendingDefer{Label: s.label, Executor: s.executor, Created: s.since, Age: now.Sub(s.since)}
+		s.m.Unlock()
+		result[i].Stack = _PC2Frame(s.created)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.Before(result[j].Created) })
+	return result
+}
+
+var (
+	_RecentM   sync.Mutex
+	_Recent    []RecentError // ring buffer, nil if disabled
+	_RecentPos int
+	_RecentLen int
+	_RecentOn  atomic.Bool
+)
+
+// RecentError a root error recorded by RecordRecent
+type RecentError struct {
+	Time time.Time
+	Err  Error
+}
+
+// RecordRecent
+// Record the last capacity root errors created by ErrByInfo and the constructors built on it, or raised by calm
+// itself such as recovered panics, 0 to disable, the default. Changing the capacity drops the errors recorded so
+// far. See RecentErrors.
+func RecordRecent(capacity int) {
+	_RecentM.Lock()
+	defer _RecentM.Unlock()
+	_Recent, _RecentPos, _RecentLen = nil, 0, 0
+	if capacity > 0 {
+		_Recent = make([]RecentError, capacity)
+	}
+	_RecentOn.Store(capacity > 0)
+}
+
+func pRecordRecent(err Error) {
+	if !_RecentOn.Load() {
+		return
+	}
+	now := time.Now()
+	_RecentM.Lock()
+	if _Recent != nil {
+		_Recent[_RecentPos] = RecentError{Time: now, Err: err}
+		_RecentPos = (_RecentPos + 1) % len(_Recent)
+		if _RecentLen < len(_Recent) {
+			_RecentLen++
+		}
+	}
+	_RecentM.Unlock()
+}
+
+// RecentErrors the root errors recorded by RecordRecent, newest first
+func RecentErrors() []RecentError {
+	_RecentM.Lock()
+	defer _RecentM.Unlock()
+	result := make([]RecentError, 0, _RecentLen)
+	for i := 1; i <= _RecentLen; i++ {
+		result = append(result, _Recent[(_RecentPos-i+len(_Recent))%len(_Recent)])
+	}
+	return result
+}
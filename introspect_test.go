@@ -0,0 +1,25 @@
+package calm
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestRecentRecordsLibraryRoots(t *testing.T) {
+	RecordRecent(8)
+	defer RecordRecent(0)
+	Run(func() { panic("boom") })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(runtime.Goexit)
+	}()
+	<-done
+	recent := RecentErrors()
+	if len(recent) != 2 {
+		t.Fatalf("%d recent errors, want 2", len(recent))
+	}
+	if (recent[0].Err.ECode() != ECancel) || (recent[1].Err.ECode() != EInternal) {
+		t.Errorf("recent errors %v, %v", recent[0].Err, recent[1].Err)
+	}
+}
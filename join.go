@@ -0,0 +1,129 @@
+package calm
+
+import "fmt"
+
+type sErrJoin struct {
+	sErrNode
+	causes []Error
+}
+
+func (e *sErrJoin) Error() string       { return PrintDetails(e, FullPrint) }
+func (e *sErrJoin) Slices() []ErrorInfo { return _ChainSlices(e) }
+func (e *sErrJoin) Causes() []Error     { return e.causes }
+
+// Next the first cause, for consumers only aware of linear chains
+func (e *sErrJoin) Next() Error { return e.causes[0] }
+
+// Unwrap expose every cause and the std error wrapped as the Meta of this slice
+func (e *sErrJoin) Unwrap() []error {
+	list := make([]error, 0, len(e.causes)+1)
+	for _, cause := range e.causes {
+		list = append(list, cause)
+	}
+	return pAppendMetaErr(list, e.info)
+}
+
+// ErrJoinByInfo
+// Return a calm.Error with its top level slice set to `info` and nesting every error of `causes`, nil ones dropped.
+// If no cause remains, this is equivalent of ErrByInfo(info).
+// Otherwise, the corresponding OnErrNest of the registered calm.IErrType will be called.
+// Stack of the caller will be captured if any of `causes` has stack captured or OnErrNest reports true.
+// This function should never fail or panic.
+func ErrJoinByInfo(info ErrorInfo, causes ...Error) Error {
+	list := make([]Error, 0, len(causes))
+	hasTrace := false
+	for _, cause := range causes {
+		if cause != nil {
+			_, trace := _ErrExtractNestPair(cause)
+			hasTrace = hasTrace || trace
+			list = append(list, cause)
+		}
+	}
+	if len(list) == 0 {
+		return ErrByInfo(info)
+	}
+	err := &sErrJoin{sErrNode: sErrNode{info: info}, causes: list}
+	report := pErrOnNestSafe(info.TCode(), err)
+	if hasTrace || report {
+		err.trace = pWithTrace()
+	}
+	return err
+}
+
+// ErrJoin Equivalent of ErrJoinByInfo(InfoCode(err), causes...), std errors are expanded as by Join
+func ErrJoin(err uint64, causes ...error) Error {
+	return ErrJoinByInfo(InfoCode(err), _JoinCauses(nil, causes)...)
+}
+
+// Join
+// Aggregate errs into one calm.Error. nil errors are dropped, and nil is returned if no error remains.
+// A single remaining error is returned as is. std errors aggregating several errors (e.g. created by errors.Join)
+// are expanded into individual causes. The top slice carries the code shared by every cause, or EInternal.
+func Join(errs ...error) Error {
+	causes := _JoinCauses(nil, errs)
+	switch len(causes) {
+	case 0:
+		return nil
+	case 1:
+		return causes[0]
+	}
+	return ErrJoinByInfo(InfoClean(_JoinCode(causes), fmt.Sprintf("%d errors", len(causes))), causes...)
+}
+
+// ThrowJoin Equivalent of Throw(ErrJoin(err, causes...))
+func ThrowJoin(err uint64, causes ...error) { Throw(ErrJoin(err, causes...)) }
+
+func _JoinCauses(list []Error, errs []error) []Error {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if typed, ok := err.(Error); ok {
+			list = append(list, typed)
+		} else if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			list = _JoinCauses(list, joined.Unwrap())
+		} else {
+			list = append(list, _AnyToError(err))
+		}
+	}
+	return list
+}
+
+func _JoinCode(causes []Error) uint64 {
+	code := MakeErrCode(causes[0].TCode(), causes[0].ECode())
+	for _, cause := range causes[1:] {
+		if MakeErrCode(cause.TCode(), cause.ECode()) != code {
+			return MakeErrCode(0, EInternal)
+		}
+	}
+	return code
+}
+
+// Walk
+// Visit every slice of err depth-first and top-down, the causes of a multi-cause slice in order.
+// depth is the nesting level of the slice, 0 for the top level one.
+// If visit returns false, the slices nested below the visited one are skipped.
+func Walk(err Error, visit func(depth int, info ErrorInfo) bool) {
+	if err != nil {
+		_Walk(err, 0, visit)
+	}
+}
+
+func _Walk(e Error, depth int, visit func(depth int, info ErrorInfo) bool) {
+	if depth > _MaxNesting {
+		return // causes forming a loop
+	}
+	slices, causes := _Unfold(e)
+	for _, slice := range slices {
+		if _IsAsync(slice.info) {
+			continue
+		}
+		if !visit(depth, slice.info) {
+			return
+		}
+		depth++
+	}
+	for _, cause := range causes {
+		_Walk(cause, depth, visit)
+	}
+}
@@ -0,0 +1,83 @@
+package calm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func _JoinFixture() Error {
+	row := ErrCleanN(ErrClean(EResNone, "row"), ECancel, "cache")
+	return ErrCleanN(Join(ErrClean(ETimeout, "db"), errors.New("plain"), nil, row), ERequest, "top")
+}
+
+func TestJoin(t *testing.T) {
+	if (Join() != nil) || (Join(nil, nil) != nil) {
+		t.Error("Join of nothing not nil")
+	}
+	single := ErrClean(EResNone, "gone")
+	if Join(nil, single) != single {
+		t.Error("single error not returned as is")
+	}
+	err := Join(ErrClean(EResNone, "a"), errors.Join(ErrClean(EResNone, "b"), nil, ErrClean(EResNone, "c")))
+	joined, ok := err.(ErrorJoin)
+	if !ok || (len(joined.Causes()) != 3) {
+		t.Fatalf("got %v, want 3 causes", err)
+	}
+	if err.ECode() != EResNone {
+		t.Errorf("code %d, want the shared EResNone", err.ECode())
+	}
+	if code := Join(ErrClean(EResNone, "a"), ErrClean(ETimeout, "b")).ECode(); code != EInternal {
+		t.Errorf("code %d of mixed causes, want EInternal", code)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	type visited struct {
+		depth int
+		clean string
+	}
+	var got []visited
+	Walk(_JoinFixture(), func(depth int, info ErrorInfo) bool {
+		got = append(got, visited{depth, info.Clean()})
+		return true
+	})
+	want := []visited{{0, "top"}, {1, "3 errors"}, {2, "db"}, {2, "plain"}, {2, "cache"}, {3, "row"}}
+	if len(got) != len(want) {
+		t.Fatalf("visited %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("slice %d: %v, want %v", i, got[i], want[i])
+		}
+	}
+	var cleans []string
+	Walk(_JoinFixture(), func(depth int, info ErrorInfo) bool {
+		cleans = append(cleans, info.Clean())
+		return info.Clean() != "cache"
+	})
+	if strings.Join(cleans, ",") != "top,3 errors,db,plain,cache" {
+		t.Errorf("visited %v, want the slices below cache skipped", cleans)
+	}
+	Walk(nil, func(int, ErrorInfo) bool {
+		t.Error("nil error visited")
+		return true
+	})
+}
+
+func TestPrintJoinTree(t *testing.T) {
+	want := "invalid request: top\n" +
+		"\tFrom[2]: internal server error: 3 errors\n" +
+		"\tCause[1/3]: sys: action timeout exceeded: db\n" +
+		"\tCause[2/3]: internal server error: plain\n" +
+		"\tCause[3/3]: sys: action cancelled: cache\n" +
+		"\t\tFrom[2]: resource: not found: row\n"
+	if got := PrintCleans(_JoinFixture()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	details := PrintDetails(_JoinFixture(), FullPrint)
+	causes := strings.TrimSuffix(want[strings.Index(want, "\tCause[1/3]"):], "\n")
+	if !strings.Contains(details, "Backtrace:") || !strings.Contains(details, causes) {
+		t.Errorf("causes missing from the detail print:\n%s", details)
+	}
+}
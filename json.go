@@ -0,0 +1,47 @@
+package calm
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// EncodeJSON
+// Encode err as a JSON object, one object per slice nesting the next one in "next", or its causes in "causes".
+// Each object carries the type and error code, the name from IErrType.ErrName and the clean message, plus the
+// detail, meta and frames as selected by option, nil for CleanEncode. A nil err is encoded as null.
+func EncodeJSON(err Error, option *EncodeOptions) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(_ToWire(err, option))
+}
+
+// DecodeJSON
+// Decode an error encoded by EncodeJSON. The result is marked as remote, see IsRemote, and its frames are printed
+// as remote frames. null is decoded as a nil Error.
+func DecodeJSON(data []byte) (Error, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+	var node sWireNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	return _FromWire(&node)
+}
+
+func (e *sErrNode) MarshalJSON() ([]byte, error)  { return EncodeJSON(e, FullEncode) }
+func (e *sErrChain) MarshalJSON() ([]byte, error) { return EncodeJSON(e, FullEncode) }
+func (e *sErrJoin) MarshalJSON() ([]byte, error)  { return EncodeJSON(e, FullEncode) }
+
+// JSONError holder of an Error for use in JSON-encoded structures, with EncodeJSON(err, FullEncode) and DecodeJSON
+type JSONError struct {
+	Err Error
+}
+
+func (e JSONError) MarshalJSON() ([]byte, error) { return EncodeJSON(e.Err, FullEncode) }
+
+func (e *JSONError) UnmarshalJSON(data []byte) (err error) {
+	e.Err, err = DecodeJSON(data)
+	return
+}
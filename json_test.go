@@ -0,0 +1,124 @@
+package calm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// _ErrTest error type with a default message and traces on every root error
+type _ErrTest struct{ uint32 }
+
+func (e _ErrTest) Type() uint32              { return e.uint32 }
+func (e _ErrTest) OnErrNest(Error) bool      { return false }
+func (e _ErrTest) OnErrRoot(Error) bool      { return true }
+func (e _ErrTest) ErrName(err uint32) string { return "test" }
+func (e _ErrTest) DefaultMsg(uint32) string  { return "default message" }
+
+var _TypeTest = AddErrType(func(id uint32) IErrType { return _ErrTest{id} })
+
+func _JSONRoundTrip(t *testing.T, err Error, option *EncodeOptions) Error {
+	t.Helper()
+	data, e := EncodeJSON(err, option)
+	if e != nil {
+		t.Fatal(e)
+	}
+	decoded, e := DecodeJSON(data)
+	if e != nil {
+		t.Fatalf("%v decoding %s", e, data)
+	}
+	return decoded
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	user := ErrAttr(ErrDetail(EResNone, "user not found", "user 42 not in shard 3"), _KeyTenant.Of("acme"))
+	custom := ErrCleanN(ErrRetryAfter(EResRetry, "busy", 3*time.Second), MakeErrCode(_TypeTest, 3), "custom")
+	err := ErrCleanN(Join(user, custom), ERequest, "lookup failed")
+	decoded := _JSONRoundTrip(t, err, FullEncode)
+	want, got := _WireSlices(err), _WireSlices(decoded)
+	if len(want) != len(got) {
+		t.Fatalf("slices %v, want %v", got, want)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("slice %d: %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	Walk(decoded, func(_ int, info ErrorInfo) bool {
+		if !IsRemote(info) {
+			t.Errorf("slice %q not marked remote", info.Clean())
+		}
+		return true
+	})
+	if tenant, ok := Lookup(decoded, _KeyTenant); !ok || (tenant != "acme") {
+		t.Errorf("attribute %q %v, want acme", tenant, ok)
+	}
+	if meta, ok := FindMeta[json.RawMessage](decoded); !ok || (string(meta) != "3000000000") {
+		t.Errorf("meta %s %v, want the retry delay", meta, ok)
+	}
+	if nothing := _JSONRoundTrip(t, nil, FullEncode); nothing != nil {
+		t.Errorf("nil decoded as %v", nothing)
+	}
+}
+
+func TestJSONCleanEncode(t *testing.T) {
+	err := ErrAttr(ErrDetail(EResNone, "user not found", "user 42 not in shard 3"), _KeyTenant.Of("acme"))
+	decoded := _JSONRoundTrip(t, err, nil)
+	if detail := decoded.Slices()[0].Detail(); detail != "user not found" {
+		t.Errorf("detail %q leaked", detail)
+	}
+	if _, ok := Lookup(decoded, _KeyTenant); ok {
+		t.Error("attribute leaked")
+	}
+}
+
+func TestJSONRemoteFrames(t *testing.T) {
+	err := ErrClean(MakeErrCode(_TypeTest, 1), "traced")
+	frames := _JSONRoundTrip(t, err, FullEncode).(interface{ pFrames() []StackFrame }).pFrames()
+	if len(frames) == 0 {
+		t.Fatal("frames dropped")
+	}
+	for _, f := range frames {
+		if !f.Remote {
+			t.Errorf("frame %+v not marked remote", f)
+		}
+	}
+	printed := PrintDetails(_JSONRoundTrip(t, err, FullEncode), ShortPrint)
+	if !strings.Contains(printed, "[remote] testing.tRunner at (") {
+		t.Errorf("remote frames not printed as such:\n%s", printed)
+	}
+	if _JSONRoundTrip(t, err, CleanEncode).(interface{ pFrames() []StackFrame }).pFrames() != nil {
+		t.Error("frames encoded by CleanEncode")
+	}
+}
+
+func TestJSONRemoteDefaultMessage(t *testing.T) {
+	err := ErrCode(MakeErrCode(_TypeTest, 1))
+	if printed := PrintCleans(_JSONRoundTrip(t, err, nil)); printed != "test: default message\n" {
+		t.Errorf("default message of the sender printed as %q", printed)
+	}
+	data := fmt.Sprintf(`{"type":%d,"code":1,"name":"remote test"}`, _TypeTest)
+	decoded, e := DecodeJSON([]byte(data))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if printed := PrintCleans(decoded); printed != "remote test\n" {
+		t.Errorf("remote error printed as %q, want the local default message left out", printed)
+	}
+}
+
+func TestJSONError(t *testing.T) {
+	data, e := json.Marshal(struct{ Err JSONError }{JSONError{ErrClean(EResNone, "gone")}})
+	if e != nil {
+		t.Fatal(e)
+	}
+	var decoded struct{ Err JSONError }
+	if e = json.Unmarshal(data, &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if (decoded.Err.Err == nil) || (decoded.Err.Err.ECode() != EResNone) || !IsRemote(decoded.Err.Err.Slices()[0]) {
+		t.Errorf("decoded %v from %s", decoded.Err.Err, data)
+	}
+}
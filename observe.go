@@ -0,0 +1,114 @@
+package calm
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	_TrackUnobserved atomic.Bool
+	_UnobservedId    atomic.Uint64
+	_UnobservedM     sync.Mutex
+	_Unobserved      = map[uint64]sUnobserved{}
+)
+
+// sUnobserved a tracked Defer completed with an error that was not observed yet
+type sUnobserved struct {
+	err     Error
+	created []uintptr
+}
+
+// TrackUnobserved
+// Enable or disable the tracking of unobserved failures, disabled by default as it captures a stack for every Defer
+// created. A tracked Defer completed with an error is unobserved until Wait, Get, Dump, Unwrap, Fold, WaitCtx, Done
+// or a completion callback is called on it. If it is garbage collected while unobserved, the failure is reported
+// with its creation stack to the handler set by SetUnhandledHandler. See CheckLeaks for an explicit check.
+// Combinators such as All, Any, Race and Then register on their inputs, which observes them right away: a failure
+// they drop, e.g. of a task losing a Race, is never reported.
+func TrackUnobserved(enabled bool) { _TrackUnobserved.Store(enabled) }
+
+// pTrack start tracking a new Defer if tracking of unobserved failures or of pending Defers is enabled
+func (s *sDefer) pTrack() {
+	unobserved, pending := _TrackUnobserved.Load(), _TrackPending.Load()
+	if !unobserved && !pending {
+		return
+	}
+	if s.created = s.creator; s.created == nil {
+		s.created = pWithTrace()
+	}
+	if unobserved {
+		s.id = _UnobservedId.Add(1)
+		runtime.SetFinalizer(s, pFinalizeDefer)
+	}
+	if pending {
+		s.pRegisterPending()
+	}
+}
+
+// pObserve mark the Defer as observed, dropping its failure from the unobserved ones
+func (s *sDefer) pObserve() {
+	if (s.id != 0) && s.observed.CompareAndSwap(false, true) {
+		_UnobservedM.Lock()
+		delete(_Unobserved, s.id)
+		_UnobservedM.Unlock()
+	}
+}
+
+// pCheckObserved record the failure of a completed Defer until it is observed
+func (s *sDefer) pCheckObserved() {
+	if (s.id == 0) || (s.err == nil) {
+		return
+	}
+	_UnobservedM.Lock()
+	if !s.observed.Load() {
+		_Unobserved[s.id] = sUnobserved{err: s.err, created: s.created}
+	}
+	_UnobservedM.Unlock()
+}
+
+func pFinalizeDefer(s *sDefer) {
+	_UnobservedM.Lock()
+	leak, ok := _Unobserved[s.id]
+	delete(_Unobserved, s.id)
+	_UnobservedM.Unlock()
+	if ok {
+		pReportUnhandled(leak.pError())
+	}
+}
+
+// pError the failure nested with the creation stack of its Defer
+func (e *sUnobserved) pError() Error {
+	info := InfoClean(EInternal, "unobserved task failure")
+	return &sErrChain{sErrNode: sErrNode{info: info, trace: e.created}, next: e.err}
+}
+
+// LeakReporter the subset of testing.TB used by CheckLeaks
+type LeakReporter interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// CheckLeaks
+// Report every tracked Defer that completed with an error not observed so far to t, in creation order, and forget
+// about them. Enable tracking with TrackUnobserved first, typically in TestMain, then call CheckLeaks at the end of
+// each test once all of its tasks completed.
+func CheckLeaks(t LeakReporter) {
+	t.Helper()
+	_UnobservedM.Lock()
+	ids := make([]uint64, 0, len(_Unobserved))
+	for id := range _Unobserved {
+		ids = append(ids, id)
+	}
+	leaks := make([]sUnobserved, 0, len(ids))
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		leaks = append(leaks, _Unobserved[id])
+		delete(_Unobserved, id)
+	}
+	_UnobservedM.Unlock()
+	for _, leak := range leaks {
+		t.Errorf("%s", PrintDetails(leak.pError(), TrimShortPrint))
+	}
+}
@@ -0,0 +1,89 @@
+package calm
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// _FakeTB a LeakReporter recording the reported errors
+type _FakeTB struct{ errs []string }
+
+func (t *_FakeTB) Helper() {}
+
+func (t *_FakeTB) Errorf(format string, args ...any) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+// _AwaitUnobserved poll until n failures are unobserved, failing the test if they are not within a few seconds
+func _AwaitUnobserved(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_UnobservedM.Lock()
+		count := len(_Unobserved)
+		_UnobservedM.Unlock()
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d unobserved failures, want %d", count, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCheckLeaks(t *testing.T) {
+	TrackUnobserved(true)
+	defer TrackUnobserved(false)
+	first := RunAsync(func() { ThrowClean(EResNone, "first") })
+	second := RunAsync(func() { ThrowClean(EResGone, "second") })
+	if err := RunAsync(func() { ThrowClean(EResNone, "observed") }).Dump(); err == nil {
+		t.Fatal("task did not fail")
+	}
+	_ = RunAsync(func() {}).Dump()
+	_AwaitUnobserved(t, 2)
+	var fake _FakeTB
+	CheckLeaks(&fake)
+	runtime.KeepAlive(first)
+	runtime.KeepAlive(second)
+	if (len(fake.errs) != 2) || !strings.Contains(fake.errs[0], "first") || !strings.Contains(fake.errs[1], "second") {
+		t.Fatalf("reported %q, want the first then the second failure", fake.errs)
+	}
+	if !strings.Contains(fake.errs[0], "unobserved task failure") {
+		t.Errorf("report %q does not name the leak", fake.errs[0])
+	}
+	CheckLeaks(&fake)
+	if len(fake.errs) != 2 {
+		t.Errorf("leaks reported twice: %q", fake.errs[2:])
+	}
+}
+
+func _LeakFailure() {
+	RunAsync(func() { ThrowClean(EResNone, "leaked") })
+}
+
+func TestUnobservedFinalizer(t *testing.T) {
+	reports := make(chan Error, 1)
+	SetUnhandledHandler(func(err Error) { reports <- err })
+	defer SetUnhandledHandler(nil)
+	TrackUnobserved(true)
+	defer TrackUnobserved(false)
+	_LeakFailure()
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case err := <-reports:
+			if (err.ECode() != EInternal) || !strings.Contains(PrintCleans(err), "leaked") {
+				t.Errorf("reported %v, want the leaked failure", err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("leaked failure not reported")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
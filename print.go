@@ -13,9 +13,11 @@ type StackPrintOptions struct {
 }
 
 type StackFrame struct {
-	Func, File string
-	Line       int
-	PC         uintptr
+	Func   string  `json:"func"`
+	File   string  `json:"file"`
+	Line   int     `json:"line"`
+	PC     uintptr `json:"-"`
+	Remote bool    `json:"-"` // the frame was decoded from another process
 }
 
 var (
@@ -26,18 +28,34 @@ var (
 )
 
 func _DefaultPrint(f *StackFrame) string {
+	if f.Remote {
+		return fmt.Sprintf("[remote] %s at (%d:%s)\n", f.Func, f.Line, f.File)
+	}
 	return fmt.Sprintf("[%016x] %s at (%d:%s)\n", f.PC, f.Func, f.Line, f.File)
 }
 
 func _DefaultShortPrint(f *StackFrame) string {
 	_, name := path.Split(f.File)
 	_, fun := path.Split(f.Func)
+	if f.Remote {
+		return fmt.Sprintf("[remote] %s at (%d:%s)\n", fun, f.Line, name)
+	}
 	return fmt.Sprintf("%s at (%d:%s)\n", fun, f.Line, name)
 }
 
 type _Slice struct {
-	info  ErrorInfo
-	trace []uintptr
+	info   ErrorInfo
+	trace  []uintptr
+	frames []StackFrame
+}
+
+func (s *_Slice) HasTrace() bool { return (s.trace != nil) || (s.frames != nil) }
+
+func (s *_Slice) Frames() []StackFrame {
+	if s.frames != nil {
+		return s.frames
+	}
+	return _PC2Frame(s.trace)
 }
 
 func _Flip(s []_Slice) {
@@ -52,23 +70,17 @@ func _Flip(s []_Slice) {
 	}
 }
 
-func _TraceError(e Error) (result []_Slice) {
-	var c any = e
-	result = make([]_Slice, 0)
-	for {
-		switch n := c.(type) {
-		case *sErrChain:
-			result = append(result, _Slice{info: n.info, trace: n.trace})
-			c = n.next
-		case *sErrNode:
-			result = append(result, _Slice{info: n.info, trace: n.trace})
-			_Flip(result)
-			return
-		}
-	}
+// _TraceError the slices of the linear part of e root-first, and the causes nested below the root-most of them
+func _TraceError(e Error) (result []_Slice, causes []Error) {
+	result, causes = _Unfold(e)
+	_Flip(result)
+	return
 }
 
 func _PrintableErrorTag(info ErrorInfo) string {
+	if remote, ok := info.(*sInfoRemote); ok {
+		return remote.name
+	}
 	if reg, ok := _Reg.Load(info.TCode()); ok {
 		return reg.(IErrType).ErrName(info.ECode())
 	} else {
@@ -80,7 +92,7 @@ func _PrintTaggedClean(b *strings.Builder, info ErrorInfo) {
 	b.WriteString(_PrintableErrorTag(info))
 	clean := info.Clean()
 	if clean == "" {
-		clean = pErrDefaultMsgSafe(info.TCode(), info.ECode())
+		clean = _DefaultMsg(info)
 	}
 	if clean != "" {
 		b.WriteString(": ")
@@ -88,17 +100,17 @@ func _PrintTaggedClean(b *strings.Builder, info ErrorInfo) {
 	}
 }
 
+// _DefaultMsg the default message of the registered calm.IErrType, none for remote slices as types are process-local
+func _DefaultMsg(info ErrorInfo) string {
+	if IsRemote(info) {
+		return ""
+	}
+	return pErrDefaultMsgSafe(info.TCode(), info.ECode())
+}
+
 func PrintCleans(error Error) string {
 	var builder strings.Builder
-	slices := _TraceError(error)
-	_PrintTaggedClean(&builder, slices[len(slices)-1].info)
-	builder.WriteString("\n")
-	nestId := len(slices)
-	for i, slice := range slices[:len(slices)-1] {
-		builder.WriteString(fmt.Sprintf("\tFrom[%d]: ", nestId-i))
-		_PrintTaggedClean(&builder, slice.info)
-		builder.WriteString("\n")
-	}
+	_PrintTree(&builder, error, "", _PrintTaggedClean, nil, nil)
 	return builder.String()
 }
 
@@ -106,45 +118,79 @@ func _PrintTaggedDetail(b *strings.Builder, info ErrorInfo) {
 	b.WriteString(_PrintableErrorTag(info))
 	detail := info.Detail()
 	if detail == "" {
-		detail = pErrDefaultMsgSafe(info.TCode(), info.ECode())
+		detail = _DefaultMsg(info)
 	}
 	if detail != "" {
 		b.WriteString(": ")
 		b.WriteString(detail)
 	}
+	_PrintAttrs(b, info)
 }
 
 func PrintDetails(error Error, option *StackPrintOptions) string {
 	var builder strings.Builder
-	slices := _TraceError(error)
-	_PrintTaggedDetail(&builder, slices[len(slices)-1].info)
-	builder.WriteString("\n")
-	nestId := len(slices)
-	for i, slice := range slices[:len(slices)-1] {
-		builder.WriteString(fmt.Sprintf("\tFrom[%d]: ", nestId-i))
-		_PrintTaggedDetail(&builder, slice.info)
-		builder.WriteString("\n")
+	var stem []StackFrame
+	if (option != nil) && option.TrimStack {
+		stem = _PC2Frame(pWithTrace())
+	}
+	_PrintTree(&builder, error, "", _PrintTaggedDetail, option, stem)
+	return builder.String()
+}
+
+// _PrintTree
+// Print the linear part of e, then each of its causes one level deeper.
+// Backtraces are printed with option if not nil, trimmed of the frames in common with stem if not nil.
+// The causes of a multi-cause slice with a trace use that trace as their stem.
+func _PrintTree(
+	b *strings.Builder, e Error, indent string,
+	tagged func(*strings.Builder, ErrorInfo), option *StackPrintOptions, stem []StackFrame,
+) {
+	slices, causes := _TraceError(e)
+	shown := make([]ErrorInfo, 0, len(slices))
+	for _, slice := range slices {
+		if !_IsAsync(slice.info) {
+			shown = append(shown, slice.info) // async slices only contribute their traces
+		}
+	}
+	tagged(b, shown[len(shown)-1])
+	b.WriteString("\n")
+	nestId := len(shown)
+	for i, info := range shown[:len(shown)-1] {
+		b.WriteString(indent)
+		b.WriteString(fmt.Sprintf("\tFrom[%d]: ", nestId-i))
+		tagged(b, info)
+		b.WriteString("\n")
 	}
 	if option != nil {
 		frames := make([][]StackFrame, 0)
 		for _, slice := range slices {
-			if slice.trace != nil {
-				frames = append(frames, _PC2Frame(slice.trace))
+			if slice.HasTrace() {
+				frames = append(frames, slice.Frames())
 			}
 		}
-		if option.TrimStack {
-			frames = append(frames, _PC2Frame(pWithTrace()))
-		}
 		if len(frames) > 0 {
-			builder.WriteString("Backtrace:\n")
+			if stem != nil {
+				frames = append(frames, stem)
+			}
 			segments := _CollapseFrames(frames)
-			if option.TrimStack {
+			if stem != nil {
 				segments = segments[:len(segments)-1]
 			}
-			_PrintSegmentedFrames(&builder, segments, option.Formatter)
+			if _CountFrames(segments) > 0 {
+				b.WriteString(indent)
+				b.WriteString("Backtrace:\n")
+				_PrintSegmentedFrames(b, indent, segments, option.Formatter)
+			}
+		}
+		if (len(causes) > 0) && slices[0].HasTrace() {
+			stem = slices[0].Frames()
 		}
 	}
-	return builder.String()
+	for i, cause := range causes {
+		b.WriteString(indent)
+		b.WriteString(fmt.Sprintf("\tCause[%d/%d]: ", i+1, len(causes)))
+		_PrintTree(b, cause, indent+"\t", tagged, option, stem)
+	}
 }
 
 func _PC2Frame(pc []uintptr) (result []StackFrame) {
@@ -192,10 +238,10 @@ func _CollapseFrames(all [][]StackFrame) (result []_Segment) {
 			}
 			lhs := &l[len(l)-level]
 			rhs := &r[len(r)-level]
-			if lhs.PC == rhs.PC {
+			if (lhs.PC == rhs.PC) && (lhs.PC != 0) {
 				continue
 			}
-			if (lhs.Func != rhs.Func) || (lhs.Line != rhs.Line) {
+			if (lhs.Func != rhs.Func) || (lhs.Line != rhs.Line) || (lhs.Remote != rhs.Remote) {
 				satisfy = false
 				break
 			}
@@ -213,6 +259,8 @@ func _CollapseFrames(all [][]StackFrame) (result []_Segment) {
 			// craft the result of the last item
 			slice := all[unfinished]
 			result[unfinished] = _Segment{Branch: slice[:len(slice)-maxCommon], Stem: slice[len(slice)-maxCommon:]}
+			// restart from the new bottom of the remaining items, the frames compared so far were sliced off
+			level = 1
 		}
 	}
 	// treat the remaining as stem, set result
@@ -220,15 +268,26 @@ func _CollapseFrames(all [][]StackFrame) (result []_Segment) {
 	return
 }
 
-func _PrintSegmentedFrames(builder *strings.Builder, s []_Segment, apply func(frame *StackFrame) string) {
+func _CountFrames(s []_Segment) (count int) {
+	for _, segment := range s {
+		count += len(segment.Branch) + len(segment.Stem)
+	}
+	return
+}
+
+func _PrintSegmentedFrames(
+	builder *strings.Builder, indent string, s []_Segment, apply func(frame *StackFrame) string,
+) {
 	nestId := len(s) // this is in reverse, the first segment has the highest nesting depth
 	for i, segment := range s {
 		depth := nestId - i
 		for _, f := range segment.Branch {
+			builder.WriteString(indent)
 			builder.WriteString(fmt.Sprintf("%d|+\t", depth))
 			builder.WriteString(apply(&f))
 		}
 		for _, f := range segment.Stem {
+			builder.WriteString(indent)
 			builder.WriteString(fmt.Sprintf("%d|", depth))
 			builder.WriteString(apply(&f))
 		}
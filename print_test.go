@@ -0,0 +1,41 @@
+package calm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func _Frames(funcs ...string) []StackFrame {
+	frames := make([]StackFrame, len(funcs))
+	for i, f := range funcs {
+		frames[i] = StackFrame{Func: f, File: "f.go", Line: i + 1}
+	}
+	return frames
+}
+
+func TestCollapseFramesRestartsCount(t *testing.T) {
+	// innermost trace first, outermost frames last. s1 and s2 differ at the same depth, k above them matches
+	all := [][]StackFrame{
+		{{Func: "k", Line: 1}, {Func: "s1", Line: 2}, {Func: "c", Line: 3}},
+		{{Func: "k", Line: 1}, {Func: "s2", Line: 2}, {Func: "c", Line: 3}},
+		{{Func: "t", Line: 1}, {Func: "c", Line: 3}},
+	}
+	got := _CollapseFrames(all)
+	want := []_Segment{
+		{Branch: []StackFrame{}, Stem: []StackFrame{{Func: "k", Line: 1}, {Func: "s1", Line: 2}}},
+		// s2 is not shared with s1, it used to be collapsed into the stem along with k
+		{Branch: []StackFrame{{Func: "k", Line: 1}, {Func: "s2", Line: 2}}, Stem: []StackFrame{}},
+		{Branch: []StackFrame{{Func: "t", Line: 1}}, Stem: []StackFrame{{Func: "c", Line: 3}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCollapseFramesSharedStem(t *testing.T) {
+	all := [][]StackFrame{_Frames("a", "b", "main"), _Frames("x", "b", "main")}
+	got := _CollapseFrames(all)
+	if (len(got[1].Stem) != 2) || (got[1].Branch[0].Func != "x") || (len(got[0].Stem) != 1) {
+		t.Errorf("got %+v", got)
+	}
+}
@@ -0,0 +1,83 @@
+package calm
+
+// Promise
+// Write side of a DeferT, completed from outside of any task, e.g. to bridge callback-based APIs.
+// A Promise is a shared handle, it is safe to copy and to complete from any goroutine.
+// Only the first completion takes effect, further ones report an ERequest error and are dropped.
+// Promises must be created with MakePromise: completing the zero Promise reports an ERequest error, and its Future is
+// the zero DeferT.
+type Promise[T any] struct {
+	d DeferT[T]
+}
+
+// MakePromise create a pending promise
+func MakePromise[T any]() Promise[T] {
+	return Promise[T]{d: pMakeDeferT[T]()}
+}
+
+// Future the DeferT completed by the promise
+func (p Promise[T]) Future() DeferT[T] { return p.d }
+
+// Resolve complete the promise with v
+func (p Promise[T]) Resolve(v T) Result {
+	return p.pSettle(v, nil)
+}
+
+// Reject complete the promise with err. A nil err is reported as an ERequest error and leaves the promise pending
+func (p Promise[T]) Reject(err error) Result {
+	e := _AnyToError(err)
+	if e == nil {
+		return ErrResult(ErrClean(ERequest, "promise rejected with nil error"))
+	}
+	var zero T
+	return p.pSettle(zero, e)
+}
+
+// Complete complete the promise with the outcome of r
+func (p Promise[T]) Complete(r ResultT[T]) Result {
+	return p.pSettle(r.val, r.err)
+}
+
+func (p Promise[T]) pSettle(v T, err Error) Result {
+	if p.d.t == nil {
+		return ErrResult(ErrClean(ERequest, "uninitialized promise"))
+	}
+	if !p.d.pComplete(v, err) {
+		return ErrResult(ErrClean(ERequest, "promise already completed"))
+	}
+	return ValResult()
+}
+
+// FromChan
+// Complete with the first value received from ch.
+// Fails with ECancel if ch is closed before a value is received.
+func FromChan[T any](ch <-chan T) DeferT[T] {
+	p := MakePromise[T]()
+	go func() {
+		if v, ok := <-ch; ok {
+			p.Resolve(v)
+		} else {
+			p.Reject(ErrClean(ECancel, "channel closed"))
+		}
+	}()
+	return p.Future()
+}
+
+// FromCallback
+// Adapt an API reporting its outcome to a callback. start is called immediately with the callback completing the
+// returned DeferT, a panic of start fails it. Only the first call to the callback takes effect.
+func FromCallback[T any](start func(done func(T, error))) DeferT[T] {
+	p := MakePromise[T]()
+	done := func(v T, err error) {
+		if e := _AnyToError(err); e != nil {
+			var zero T
+			p.pSettle(zero, e)
+		} else {
+			p.pSettle(v, nil)
+		}
+	}
+	if res := Run(func() { start(done) }); res.err != nil {
+		p.Reject(res.err)
+	}
+	return p.Future()
+}
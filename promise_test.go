@@ -0,0 +1,24 @@
+package calm
+
+import "testing"
+
+func TestPromiseSingleCompletion(t *testing.T) {
+	p := MakePromise[int]()
+	if err := p.Resolve(1).Dump(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Reject(ErrClean(EResNone, "late")).Dump(); (err == nil) || (err.ECode() != ERequest) {
+		t.Errorf("second completion got %v, want ERequest", err)
+	}
+	if v, err := p.Future().Dump(); (v != 1) || (err != nil) {
+		t.Errorf("future got %d, %v", v, err)
+	}
+}
+
+func TestZeroPromise(t *testing.T) {
+	var p Promise[int]
+	err := p.Resolve(1).Dump()
+	if (err == nil) || (err.ECode() != ERequest) || (err.Slices()[0].Clean() != "uninitialized promise") {
+		t.Errorf("got %v, want uninitialized promise", err)
+	}
+}
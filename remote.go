@@ -0,0 +1,214 @@
+package calm
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// EncodeOptions controls what the encoders emit beside the codes, names and clean messages of each slice.
+// A nil *EncodeOptions is equivalent to CleanEncode.
+type EncodeOptions struct {
+	// Detail emit Detail() when it differs from Clean()
+	Detail bool
+	// Meta emit Meta() when it is JSON-encodable
+	Meta bool
+	// Frames emit the symbolized frames of captured traces
+	Frames bool
+	// PCs emit captured traces as raw program counters with the build ID instead of frames, binary codec only
+	PCs bool
+	// Attrs emit the JSON-encodable attributes, see InfoAttr
+	Attrs bool
+	// MaxBytes cap of the encoded size, 0 for none, binary codec only. See EncodeWire
+	MaxBytes int
+}
+
+var (
+	FullEncode  = &EncodeOptions{Detail: true, Meta: true, Frames: true, Attrs: true}
+	CleanEncode = &EncodeOptions{Detail: false, Meta: false, Frames: false, Attrs: false}
+)
+
+// sWireNode format-neutral form of one slice shared by all encoders, a slice nests either Next or Causes
+type sWireNode struct {
+	Type   uint32                     `json:"type"`
+	Code   uint32                     `json:"code"`
+	Name   string                     `json:"name,omitempty"`
+	Clean  string                     `json:"clean,omitempty"`
+	Detail string                     `json:"detail,omitempty"`
+	Meta   json.RawMessage            `json:"meta,omitempty"`
+	Attrs  map[string]json.RawMessage `json:"attrs,omitempty"`
+	Frames []StackFrame               `json:"frames,omitempty"`
+	PCs    []int64                    `json:"-"` // offsets of raw program counters from _PCBase, binary codec only
+	Trunc  bool                       `json:"truncated,omitempty"`
+	Next   *sWireNode                 `json:"next,omitempty"`
+	Causes []*sWireNode               `json:"causes,omitempty"`
+}
+
+func _ToWire(e Error, option *EncodeOptions) *sWireNode {
+	slices, causes := _Unfold(e)
+	var head, tail *sWireNode
+	for i := range slices {
+		if _IsAsync(slices[i].info) {
+			continue
+		}
+		node := _SliceToWire(&slices[i], option)
+		if tail == nil {
+			head = node
+		} else {
+			tail.Next = node
+		}
+		tail = node
+	}
+	for _, cause := range causes {
+		tail.Causes = append(tail.Causes, _ToWire(cause, option))
+	}
+	return head
+}
+
+func _SliceToWire(s *_Slice, option *EncodeOptions) *sWireNode {
+	if option == nil {
+		option = CleanEncode
+	}
+	info := s.info
+	node := &sWireNode{Type: info.TCode(), Code: info.ECode(), Name: _PrintableErrorTag(info)}
+	node.Clean = pCleanSafe(info)
+	if node.Clean == "" {
+		node.Clean = _DefaultMsg(info)
+	}
+	if option.Detail {
+		if detail := pDetailSafe(info); detail != node.Clean {
+			node.Detail = detail
+		}
+	}
+	if remote, ok := info.(*sInfoRemote); ok {
+		node.Trunc = remote.truncated
+		if option.Detail {
+			node.Detail = remote.detail
+		}
+	}
+	if option.Meta {
+		node.Meta = pMetaJSONSafe(info)
+	}
+	if option.Attrs {
+		node.Attrs = _AttrsToWire(pAttrsSafe(info))
+	}
+	if option.PCs && (s.trace != nil) {
+		node.PCs = _PCOffsets(s.trace)
+	} else if (option.Frames || option.PCs) && s.HasTrace() {
+		node.Frames = s.Frames()
+	}
+	return node
+}
+
+func pCleanSafe(info ErrorInfo) (res string) {
+	defer func() { _ = recover() }()
+	return info.Clean()
+}
+
+func pDetailSafe(info ErrorInfo) (res string) {
+	defer func() { _ = recover() }()
+	return info.Detail()
+}
+
+func pMetaJSONSafe(info ErrorInfo) json.RawMessage { return pJSONSafe(pMetaSafe(info)) }
+
+// pJSONSafe the JSON encoding of v, errors being encoded as their message. nil if v is nil or not encodable
+func pJSONSafe(v any) (res json.RawMessage) {
+	defer func() {
+		if recover() != nil {
+			res = nil
+		}
+	}()
+	if v == nil {
+		return nil
+	}
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return data
+	}
+	return nil
+}
+
+// _FromWire reconstruct a remote error, rejecting malformed input
+func _FromWire(n *sWireNode) (Error, error) {
+	if n == nil {
+		return nil, errors.New("calm: missing error slice")
+	}
+	info := &sInfoRemote{
+		sInfoCode: sInfoCode{fCode: MakeErrCode(n.Type, n.Code)},
+		name:      n.Name, clean: n.Clean, detail: n.Detail, meta: n.Meta, truncated: n.Trunc,
+		attrs: _AttrsFromWire(n.Attrs),
+	}
+	node := sErrNode{info: info, frames: _RemoteFrames(n.Frames)}
+	if len(n.Causes) > 0 {
+		causes := make([]Error, 0, len(n.Causes))
+		for _, c := range n.Causes {
+			cause, err := _FromWire(c)
+			if err != nil {
+				return nil, err
+			}
+			causes = append(causes, cause)
+		}
+		return &sErrJoin{sErrNode: node, causes: causes}, nil
+	}
+	if n.Next != nil {
+		next, err := _FromWire(n.Next)
+		if err != nil {
+			return nil, err
+		}
+		return &sErrChain{sErrNode: node, next: next}, nil
+	}
+	return &node, nil
+}
+
+func _RemoteFrames(frames []StackFrame) []StackFrame {
+	if len(frames) == 0 {
+		return nil
+	}
+	result := make([]StackFrame, len(frames))
+	for i, f := range frames {
+		result[i] = StackFrame{Func: f.Func, File: f.File, Line: f.Line, PC: f.PC, Remote: true}
+	}
+	return result
+}
+
+type sInfoRemote struct {
+	sInfoCode
+	name, clean, detail string
+	meta                json.RawMessage
+	truncated           bool   // parts of this slice or of the nested ones were dropped by the sender
+	attrs               []Attr // values of the remote attributes are json.RawMessage
+}
+
+func (e *sInfoRemote) Clean() string { return e.clean }
+
+func (e *sInfoRemote) Detail() string {
+	detail := e.detail
+	if detail == "" {
+		detail = e.clean
+	}
+	if e.truncated && (detail != "") {
+		detail += " [truncated]"
+	} else if e.truncated {
+		detail = "[truncated]"
+	}
+	return detail
+}
+
+// Meta the raw JSON of the remote meta, if any
+func (e *sInfoRemote) Meta() any {
+	if e.meta == nil {
+		return nil
+	}
+	return e.meta
+}
+
+func (e *sInfoRemote) Attrs() []Attr { return e.attrs }
+
+// IsRemote report if info was decoded from another process.
+// Type codes of remote slices are those of the sending process, and may not match local registrations.
+func IsRemote(info ErrorInfo) bool {
+	_, ok := info.(*sInfoRemote)
+	return ok
+}
@@ -1,6 +1,10 @@
 package calm
 
-import "fmt"
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
 
 type Result struct {
 	err Error
@@ -78,23 +82,87 @@ func _AnyToError(o any) Error {
 	}
 }
 
+type sInfoPanic struct {
+	sInfoCode
+	val any
+}
+
+func (e *sInfoPanic) Clean() string  { return "panic" }
+func (e *sInfoPanic) Detail() string { return "panic: " + fmt.Sprint(e.val) }
+func (e *sInfoPanic) Meta() any      { return e.val }
+
+// pPanicToError
+// Convert a recovered panic value to an Error. A calm.Error is returned as is, any other value is kept as the Meta
+// of a new EInternal error carrying the stack of the panic site. Must be called from the deferred function that
+// recovered, while the stack of the panicking goroutine is still available.
+func pPanicToError(o any) Error {
+	if o == nil {
+		return nil
+	}
+	if err, ok := o.(Error); ok {
+		return err
+	}
+	info := &sInfoPanic{sInfoCode: sInfoCode{fCode: EInternal}, val: o}
+	return pErrRoot(info, _TrimTrace(pWithTrace(), "runtime.gopanic"), nil)
+}
+
+// pRecovered
+// Convert the outcome observed by a deferred recover into an Error, nil if the task returned normally.
+// A nil recovered value without a normal return means the goroutine is exiting through runtime.Goexit (or panicked
+// with nil), which is reported as an ECancel carrying the stack of the exiting goroutine.
+// Must be called from the deferred function that recovered.
+func pRecovered(o any, normal bool) Error {
+	if o != nil {
+		return pPanicToError(o)
+	}
+	if normal {
+		return nil
+	}
+	return pErrRoot(InfoClean(ECancel, "goroutine exited"), _TrimTrace(pWithTrace(), "runtime.Goexit"), nil)
+}
+
+// _TrimTrace drop the frames above the first call to fn, and the runtime frames right below it
+func _TrimTrace(pc []uintptr, fn string) []uintptr {
+	for i := range pc {
+		if _FuncNameOf(pc[i]) != fn {
+			continue
+		}
+		i++
+		for i < len(pc) && strings.HasPrefix(_FuncNameOf(pc[i]), "runtime.") {
+			i++
+		}
+		return pc[i:]
+	}
+	return pc
+}
+
+func _FuncNameOf(pc uintptr) string {
+	if f := runtime.FuncForPC(pc - 1); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
 func Run(exec func()) (ret Result) {
+	normal := false
 	defer func() {
-		o := recover()
-		if o != nil {
-			ret = ErrResult(_AnyToError(o))
+		if err := pRecovered(recover(), normal); err != nil {
+			ret = ErrResult(err)
 		}
 	}()
 	exec()
+	normal = true
 	return ValResult()
 }
 
 func RunT[T any](exec func() T) (ret ResultT[T]) {
+	normal := false
 	defer func() {
-		o := recover()
-		if o != nil {
-			ret = ErrResultT[T](_AnyToError(o))
+		if err := pRecovered(recover(), normal); err != nil {
+			ret = ErrResultT[T](err)
 		}
 	}()
-	return ValResultT(exec())
+	val := exec()
+	normal = true
+	return ValResultT(val)
 }
@@ -0,0 +1,164 @@
+package calm
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Backoff the delay before retry number attempt (starting at 1), prev being the delay before the previous one
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// ConstantBackoff wait the same delay before every retry
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(int, time.Duration) time.Duration { return delay }
+}
+
+// ExponentialBackoff wait base, doubled on each retry up to max
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		delay := base
+		for i := 1; (i < attempt) && (delay < max); i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// DecorrelatedJitter wait a random delay between base and three times the previous one, up to max
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	return func(_ int, prev time.Duration) time.Duration {
+		upper := prev * 3
+		if upper <= base {
+			return base
+		}
+		delay := base + time.Duration(rand.Int63n(int64(upper-base)))
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// RetryPolicy controls how Retry re-runs a failing task
+type RetryPolicy struct {
+	// MaxAttempts total number of attempts, including the first one. There is always at least one attempt
+	MaxAttempts int
+	// Deadline overall time budget from the start of the first attempt, 0 for none.
+	// It is checked between attempts only: a running attempt is never interrupted, a retry is not started if its
+	// delay would end past the deadline. Bound the attempts themselves, e.g. with a context, to enforce it strictly
+	Deadline time.Duration
+	// Backoff the delay between attempts, no delay if nil
+	Backoff Backoff
+	// Retryable decides which errors are retried, Retryable if nil
+	Retryable func(err Error) bool
+}
+
+var DefaultRetry = &RetryPolicy{MaxAttempts: 3, Backoff: ExponentialBackoff(100*time.Millisecond, 5*time.Second)}
+
+// RetryAttempt record of a failed attempt
+type RetryAttempt struct {
+	// Err the error of the attempt
+	Err Error
+	// Delay the wait after the attempt, 0 for the last one
+	Delay time.Duration
+}
+
+// RetryHistory every failed attempt of a Retry, the Meta of its ENetMaxRetry slice
+type RetryHistory []RetryAttempt
+
+func (h RetryHistory) String() string {
+	var b strings.Builder
+	for i, attempt := range h {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(fmt.Sprintf("attempt %d: ", i+1))
+		_PrintTaggedClean(&b, _TopInfo(attempt.Err))
+		if attempt.Delay > 0 {
+			b.WriteString(fmt.Sprintf(", retried after %v", attempt.Delay))
+		}
+	}
+	return b.String()
+}
+
+// Retryable report if the top slice of err carries a code its registered IErrType declares retryable, see IErrRetry
+func Retryable(err Error) bool { return pErrRetryableSafe(err.TCode(), err.ECode()) }
+
+// Retry
+// Run exec until it succeeds, following policy, DefaultRetry if nil. Errors rejected by policy.Retryable are returned
+// as is. The delay before a retry is the larger of the backoff and the RetryAfter meta of the error, if any. For a
+// remote error the meta is decoded from the integer RetryAfter is encoded to.
+// If the attempts or the deadline are exhausted, the last error is nested in an ENetMaxRetry error whose meta is the
+// RetryHistory of all attempts.
+func Retry(policy *RetryPolicy, exec func()) Result {
+	if policy == nil {
+		policy = DefaultRetry
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+	start := time.Now()
+	var history RetryHistory
+	delay := time.Duration(0)
+	for attempt := 1; ; attempt++ {
+		err := Run(exec).Dump()
+		if err == nil {
+			return ValResult()
+		}
+		if !retryable(err) {
+			return ErrResult(err)
+		}
+		history = append(history, RetryAttempt{Err: err})
+		if attempt >= policy.MaxAttempts {
+			break
+		}
+		if policy.Backoff != nil {
+			delay = policy.Backoff(attempt, delay)
+		}
+		if hint, ok := _RetryAfterHint(err); ok && (time.Duration(hint) > delay) {
+			delay = time.Duration(hint)
+		}
+		if (policy.Deadline > 0) && (time.Since(start)+delay > policy.Deadline) {
+			break
+		}
+		history[len(history)-1].Delay = delay
+		time.Sleep(delay)
+	}
+	last := history[len(history)-1].Err
+	msg := fmt.Sprintf("gave up after %d attempts", len(history))
+	return ErrResult(ErrStringerN(last, ENetMaxRetry, msg, history))
+}
+
+// _RetryAfterHint the first RetryAfter meta of err as by FindMeta, remote metas being decoded from JSON
+func _RetryAfterHint(err Error) (hint RetryAfter, found bool) {
+	Walk(err, func(_ int, info ErrorInfo) bool {
+		if !found {
+			hint, found = _AttrValue[RetryAfter](pMetaSafe(info))
+		}
+		return !found
+	})
+	return
+}
+
+// RetryT Equivalent of Retry for a task producing a value
+func RetryT[T any](policy *RetryPolicy, exec func() T) ResultT[T] {
+	var val T
+	res := Retry(policy, func() { val = exec() })
+	return ResultT[T]{val: val, Result: res}
+}
+
+// RetryResult Equivalent of Retry for a task reporting its outcome as a Result
+func RetryResult(policy *RetryPolicy, exec func() Result) Result {
+	return Retry(policy, func() { exec().Get() })
+}
+
+// RetryResultT Equivalent of Retry for a task reporting its outcome as a ResultT
+func RetryResultT[T any](policy *RetryPolicy, exec func() ResultT[T]) ResultT[T] {
+	return RetryT(policy, func() T { return exec().Get() })
+}
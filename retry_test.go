@@ -0,0 +1,112 @@
+package calm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffSchedule(t *testing.T) {
+	exponential := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := exponential(attempt+1, 0); got != want*time.Millisecond {
+			t.Errorf("exponential attempt %d: %v, want %v", attempt+1, got, want*time.Millisecond)
+		}
+	}
+	if got := ConstantBackoff(time.Second)(7, time.Minute); got != time.Second {
+		t.Errorf("constant: %v", got)
+	}
+	jitter := DecorrelatedJitter(10*time.Millisecond, 100*time.Millisecond)
+	prev := time.Duration(0)
+	for attempt := 1; attempt <= 20; attempt++ {
+		upper := 3 * prev
+		if upper < 10*time.Millisecond {
+			upper = 10 * time.Millisecond
+		} else if upper > 100*time.Millisecond {
+			upper = 100 * time.Millisecond
+		}
+		delay := jitter(attempt, prev)
+		if (delay < 10*time.Millisecond) || (delay > upper) {
+			t.Fatalf("jitter attempt %d: %v after %v", attempt, delay, prev)
+		}
+		prev = delay
+	}
+}
+
+// _Failing a task failing with the errors of errs in turn, then succeeding
+func _Failing(errs ...Error) (exec func(), attempts *int) {
+	attempts = new(int)
+	return func() {
+		*attempts++
+		if *attempts <= len(errs) {
+			Throw(errs[*attempts-1])
+		}
+	}, attempts
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	busy := ErrClean(EResRetry, "busy")
+	exec, attempts := _Failing(busy, busy, busy, busy)
+	policy := &RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(time.Millisecond)}
+	err := Retry(policy, exec).Dump()
+	if (err == nil) || (err.ECode() != ENetMaxRetry) || (*attempts != 3) {
+		t.Fatalf("got %v after %d attempts, want ENetMaxRetry after 3", err, *attempts)
+	}
+	if slices := err.Slices(); (len(slices) != 2) || (slices[1].Clean() != "busy") {
+		t.Errorf("last error not nested: %v", err)
+	}
+	history, ok := FindMeta[RetryHistory](err)
+	if !ok || (len(history) != 3) {
+		t.Fatalf("history %v", history)
+	}
+	for i, attempt := range history {
+		want := time.Millisecond
+		if i == len(history)-1 {
+			want = 0
+		}
+		if (attempt.Err != busy) || (attempt.Delay != want) {
+			t.Errorf("attempt %d: %+v, want a delay of %v", i+1, attempt, want)
+		}
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	denied := ErrClean(EDenied, "denied")
+	exec, attempts := _Failing(denied, denied)
+	if err := Retry(&RetryPolicy{MaxAttempts: 3}, exec).Dump(); (err != denied) || (*attempts != 1) {
+		t.Errorf("got %v after %d attempts, want the error as is after 1", err, *attempts)
+	}
+}
+
+func TestRetryNilPolicy(t *testing.T) {
+	busy := ErrClean(EResRetry, "busy")
+	exec, attempts := _Failing(busy)
+	if err := Retry(nil, exec).Dump(); (err != nil) || (*attempts != 2) {
+		t.Errorf("got %v after %d attempts, want a success on the second", err, *attempts)
+	}
+}
+
+func TestRetryAfterHint(t *testing.T) {
+	local := ErrRetryAfter(EResRetry, "busy", 5*time.Millisecond)
+	remote, e := DecodeWire(EncodeWire(local, FullEncode))
+	if e != nil {
+		t.Fatal(e)
+	}
+	for name, err := range map[string]Error{"local": local, "remote": remote} {
+		exec, _ := _Failing(err, err)
+		policy := &RetryPolicy{MaxAttempts: 2, Backoff: ConstantBackoff(time.Microsecond)}
+		history, _ := FindMeta[RetryHistory](Retry(policy, exec).Dump())
+		if (len(history) != 2) || (history[0].Delay != 5*time.Millisecond) {
+			t.Errorf("%s: history %v, want the hinted delay", name, history)
+		}
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	busy := ErrClean(EResRetry, "busy")
+	exec, attempts := _Failing(busy, busy, busy)
+	policy := &RetryPolicy{MaxAttempts: 5, Deadline: 10 * time.Millisecond, Backoff: ConstantBackoff(time.Hour)}
+	err := Retry(policy, exec).Dump()
+	if (err == nil) || (err.ECode() != ENetMaxRetry) || (*attempts != 1) {
+		t.Errorf("got %v after %d attempts, want to give up before a retry past the deadline", err, *attempts)
+	}
+}
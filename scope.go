@@ -0,0 +1,147 @@
+package calm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ScopeMode how a Scope reacts to the failure of a child task
+type ScopeMode int
+
+const (
+	// FailFast cancel the context of the scope on the first failure, the failures it causes are not reported
+	FailFast ScopeMode = iota
+	// CollectAll let the other children run to completion, and report every failure
+	CollectAll
+)
+
+// Scope
+// Group of child tasks sharing a context, that all complete before Wait returns.
+// Children are started with Go or ScopeGoT on the DefaultExecutor, and may start further children in the same scope.
+// Once Wait returned, the scope is closed: its context is cancelled and new children are rejected with ERequest.
+// Prefer RunScope, which makes sure Wait is called.
+type Scope struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	mode    ScopeMode
+	m       sync.Mutex
+	cond    sync.Cond
+	pending int
+	closed  bool
+	errs    []Error
+	cause   Error // failure the scope cancelled itself on, nil if it did not
+}
+
+// NewScope create a scope whose context derives from ctx
+func NewScope(ctx context.Context, mode ScopeMode) *Scope {
+	s := &Scope{mode: mode}
+	s.ctx, s.cancel = context.WithCancelCause(ctx)
+	s.cond.L = &s.m
+	return s
+}
+
+// RunScope
+// Run body with a new scope, and wait for all the children it started.
+// A failure of body is reported along with the failures of the children, and cancels the scope in either mode.
+func RunScope(ctx context.Context, mode ScopeMode, body func(s *Scope)) Error {
+	s := NewScope(ctx, mode)
+	if err := Run(func() { body(s) }).err; err != nil {
+		s.pSettle(err, true)
+	}
+	return s.Wait()
+}
+
+// Context the context of the scope, passed to every child
+func (s *Scope) Context() context.Context { return s.ctx }
+
+// Go start exec as a child of the scope
+func (s *Scope) Go(exec func(ctx context.Context)) Defer {
+	ret := pMakeDefer()
+	s.pStart(ret, func() { ret.pApply(func() { exec(s.ctx) }) })
+	return ret
+}
+
+// ScopeGoT Equivalent of s.Go for a task producing a value
+func ScopeGoT[T any](s *Scope, exec func(ctx context.Context) T) DeferT[T] {
+	ret := pMakeDeferT[T]()
+	s.pStart(ret.Defer, func() { ret.pApply(func() T { return exec(s.ctx) }) })
+	return ret
+}
+
+func (s *Scope) pStart(d Defer, task func()) {
+	s.m.Lock()
+	if s.closed {
+		s.m.Unlock()
+		d.pComplete(ErrClean(ERequest, "scope closed"))
+		return
+	}
+	s.pending++
+	s.m.Unlock()
+	run := func() {
+		defer s.pFinish(d)
+		task()
+	}
+	executor := DefaultExecutor()
+	d.pState().pSetExecutor(executor)
+	if err := pExecuteSafe(executor, run); err != nil {
+		d.pComplete(err)
+		s.pFinish(d)
+	}
+}
+
+func (s *Scope) pFinish(d Defer) {
+	state := d.pState()
+	state.pObserve() // reported by Wait
+	s.pSettle(state.err, s.mode == FailFast)
+	s.m.Lock()
+	if s.pending--; s.pending == 0 {
+		s.cond.Broadcast()
+	}
+	s.m.Unlock()
+}
+
+// pSettle record a failure, cancelling the scope on it if abort is set. Once the scope cancelled itself, failures
+// caused by the cancellation are dropped: context.Canceled, and the errors of WaitCtx and GetCtx nesting the cause
+func (s *Scope) pSettle(err Error, abort bool) {
+	if err == nil {
+		return
+	}
+	s.m.Lock()
+	if (s.cause != nil) && (errors.Is(err, context.Canceled) || errors.Is(err, s.cause)) {
+		s.m.Unlock()
+		return
+	}
+	s.errs = append(s.errs, err)
+	if abort = abort && (s.cause == nil); abort {
+		s.cause = err
+	}
+	s.m.Unlock()
+	if abort {
+		s.cancel(err)
+	}
+}
+
+// Wait
+// Wait for every child to complete, then close the scope.
+// Returns nil if all of them succeeded, the failure if a single one failed, and otherwise an error nesting every
+// failure as a cause in completion order, see Join, in either mode. Failures caused by the scope cancelling itself
+// are not reported: context.Canceled, and the errors of WaitCtx and GetCtx nesting the failure it cancelled on.
+func (s *Scope) Wait() Error {
+	s.m.Lock()
+	for s.pending > 0 {
+		s.cond.Wait()
+	}
+	s.closed = true
+	errs := s.errs
+	s.m.Unlock()
+	s.cancel(nil)
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(errs) == 1:
+		return errs[0]
+	}
+	return ErrJoinByInfo(InfoClean(_JoinCode(errs), fmt.Sprintf("%d tasks failed", len(errs))), errs...)
+}
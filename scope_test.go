@@ -0,0 +1,88 @@
+package calm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScopeFailFastReportsFirstFailure(t *testing.T) {
+	err := RunScope(context.Background(), FailFast, func(s *Scope) {
+		for i := 0; i < 4; i++ {
+			s.Go(func(ctx context.Context) {
+				<-ctx.Done()
+				Throw(Classify(ctx.Err()))
+			})
+		}
+		s.Go(func(ctx context.Context) { ThrowClean(EResNone, "missing") })
+	})
+	if (err == nil) || (err.ECode() != EResNone) || (len(err.Slices()) != 1) {
+		t.Fatalf("got %v, want the EResNone failure alone", err)
+	}
+}
+
+func TestScopeCollectAllJoins(t *testing.T) {
+	err := RunScope(context.Background(), CollectAll, func(s *Scope) {
+		s.Go(func(ctx context.Context) { ThrowClean(EResNone, "missing") })
+		s.Go(func(ctx context.Context) { ThrowClean(EResNone, "gone") })
+		s.Go(func(ctx context.Context) {})
+	})
+	causes := 0
+	Walk(err, func(depth int, info ErrorInfo) bool {
+		if depth == 1 {
+			causes++
+		}
+		return true
+	})
+	if (err == nil) || (err.ECode() != EResNone) || (causes != 2) {
+		t.Fatalf("got %v, want 2 EResNone causes", err)
+	}
+}
+
+func TestScopeBodyFailureDropsCancellations(t *testing.T) {
+	err := RunScope(context.Background(), CollectAll, func(s *Scope) {
+		s.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			Throw(Classify(ctx.Err()))
+		})
+		ThrowClean(ERequest, "bad input")
+	})
+	if (err == nil) || (err.ECode() != ERequest) || (len(err.Slices()) != 1) {
+		t.Fatalf("got %v, want the body failure alone", err)
+	}
+}
+
+func TestScopeBodyFailureDropsWaitCtx(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	err := RunScope(context.Background(), CollectAll, func(s *Scope) {
+		s.Go(func(ctx context.Context) { RunAsync(func() { <-block }).GetCtx(ctx) })
+		ThrowClean(ERequest, "bad input")
+	})
+	if (err == nil) || (err.ECode() != ERequest) || (len(err.Slices()) != 1) {
+		t.Fatalf("got %v, want the body failure alone", err)
+	}
+}
+
+func TestScopeFailFastJoins(t *testing.T) {
+	err := RunScope(context.Background(), FailFast, func(s *Scope) {
+		s.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			ThrowClean(EResGone, "flushed")
+		})
+		s.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			Throw(Classify(ctx.Err()))
+		})
+		s.Go(func(ctx context.Context) { ThrowClean(EResNone, "missing") })
+	})
+	var codes []uint32
+	Walk(err, func(depth int, info ErrorInfo) bool {
+		if depth == 1 {
+			codes = append(codes, info.ECode())
+		}
+		return true
+	})
+	if (err == nil) || (len(codes) != 2) || (codes[0] != EResNone) || (codes[1] != EResGone) {
+		t.Fatalf("got %v, want the EResNone then EResGone failures", err)
+	}
+}
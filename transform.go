@@ -0,0 +1,126 @@
+package calm
+
+// Pair values of two results combined by Zip
+type Pair[A any, B any] struct {
+	First  A
+	Second B
+}
+
+// Triple values of three results combined by Zip3
+type Triple[A any, B any, C any] struct {
+	First  A
+	Second B
+	Third  C
+}
+
+// Map
+// Transform the value of r with f. If r is an error, it is propagated as is and f is never called.
+// Errors raised by f are captured in the returned result.
+func Map[T any, U any](r ResultT[T], f func(T) U) ResultT[U] {
+	if r.err != nil {
+		return ErrResultT[U](r.err)
+	}
+	return RunT(func() U { return f(r.val) })
+}
+
+// FlatMap
+// Continue with the result of f on the value of r. If r is an error, it is propagated as is and f is never called.
+// Errors raised by f are captured in the returned result.
+func FlatMap[T any, U any](r ResultT[T], f func(T) ResultT[U]) ResultT[U] {
+	if r.err != nil {
+		return ErrResultT[U](r.err)
+	}
+	return RunT(func() U { return f(r.val).Get() })
+}
+
+// AndThen Equivalent of FlatMap(r, f)
+func AndThen[T any, U any](r ResultT[T], f func(T) ResultT[U]) ResultT[U] { return FlatMap(r, f) }
+
+// MapErr
+// Nest the error of r with the slice returned by f, following the rules of ErrNestByInfo.
+// A value of r is propagated as is and f is never called. Errors raised by f are captured in the returned result.
+func MapErr[T any](r ResultT[T], f func(Error) ErrorInfo) ResultT[T] {
+	if r.err == nil {
+		return r
+	}
+	return FlatMap(RunT(func() ErrorInfo { return f(r.err) }), func(info ErrorInfo) ResultT[T] {
+		return ErrResultT[T](ErrNestByInfo(r.err, info))
+	})
+}
+
+// Recover
+// Replace the error of r with the value returned by f. A value of r is propagated as is and f is never called.
+// Errors raised by f are captured in the returned result.
+func Recover[T any](r ResultT[T], f func(Error) T) ResultT[T] {
+	if r.err == nil {
+		return r
+	}
+	return RunT(func() T { return f(r.err) })
+}
+
+// OrElse
+// Replace the error of r with the result returned by f. A value of r is propagated as is and f is never called.
+// Errors raised by f are captured in the returned result.
+func OrElse[T any](r ResultT[T], f func(Error) ResultT[T]) ResultT[T] {
+	if r.err == nil {
+		return r
+	}
+	return RunT(func() T { return f(r.err).Get() })
+}
+
+// OrDefault The value of r, or def if r is an error
+func OrDefault[T any](r ResultT[T], def T) T {
+	if r.err != nil {
+		return def
+	}
+	return r.val
+}
+
+// Zip Combine the values of a and b, or the first error of them
+func Zip[A any, B any](a ResultT[A], b ResultT[B]) ResultT[Pair[A, B]] {
+	if err := _FirstErr(a.Result, b.Result); err != nil {
+		return ErrResultT[Pair[A, B]](err)
+	}
+	return ValResultT(Pair[A, B]{First: a.val, Second: b.val})
+}
+
+// Zip3 Combine the values of a, b and c, or the first error of them
+func Zip3[A any, B any, C any](a ResultT[A], b ResultT[B], c ResultT[C]) ResultT[Triple[A, B, C]] {
+	if err := _FirstErr(a.Result, b.Result, c.Result); err != nil {
+		return ErrResultT[Triple[A, B, C]](err)
+	}
+	return ValResultT(Triple[A, B, C]{First: a.val, Second: b.val, Third: c.val})
+}
+
+// Collect Combine the values of rs in order, or the first error of them
+func Collect[T any](rs []ResultT[T]) ResultT[[]T] {
+	vals := make([]T, 0, len(rs))
+	for _, r := range rs {
+		if r.err != nil {
+			return ErrResultT[[]T](r.err)
+		}
+		vals = append(vals, r.val)
+	}
+	return ValResultT(vals)
+}
+
+// Partition Split rs into the values and the errors they hold, both in order
+func Partition[T any](rs []ResultT[T]) (vals []T, errs []Error) {
+	for _, r := range rs {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		} else {
+			vals = append(vals, r.val)
+		}
+	}
+	return
+}
+
+func _FirstErr(rs ...Result) Error {
+	for _, r := range rs {
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
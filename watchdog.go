@@ -0,0 +1,238 @@
+package calm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StuckWait a wait on a Defer exceeding the threshold set by SetWaitWatchdog
+type StuckWait struct {
+	Waited time.Duration
+	Label  string // label of the Defer, see Defer.SetLabel
+	// Waiter stack of the waiting goroutine
+	Waiter []StackFrame
+	// Creator creation stack of the Defer, nil unless SetAsyncTrace, TrackUnobserved or TrackPending was enabled
+	Creator []StackFrame
+	// Task current stack of the goroutine running the task, nil if it is not running or was started before
+	Task []StackFrame
+}
+
+func (w *StuckWait) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("calm: wait stuck for %s", w.Waited.Round(time.Millisecond)))
+	if w.Label != "" {
+		b.WriteString(" on ")
+		b.WriteString(w.Label)
+	}
+	b.WriteString("\n")
+	for _, part := range []struct {
+		name   string
+		frames []StackFrame
+	}{{"Waiter", w.Waiter}, {"Creator", w.Creator}, {"Task", w.Task}} {
+		if part.frames != nil {
+			b.WriteString(part.name)
+			b.WriteString(":\n")
+			for i := range part.frames {
+				b.WriteString(_DefaultPrint(&part.frames[i]))
+			}
+		}
+	}
+	return b.String()
+}
+
+type sWatchdog struct {
+	threshold time.Duration
+	hook      func(*StuckWait)
+}
+
+var _Watchdog atomic.Pointer[sWatchdog]
+
+// SetWaitWatchdog
+// Report waits on a Defer, by Wait, Get, Unwrap, Fold or Dump, lasting longer than threshold to hook, 0 to disable,
+// the default. A nil hook prints the report to the standard error. The hook is called from the waiting goroutine,
+// which keeps waiting once it returns.
+// While enabled, waits forming a cycle, i.e. a task awaiting a Defer whose task is, directly or not, awaiting the
+// Defer of the first one, fail with an EInternal error carrying the stacks of the waits instead of deadlocking. Only
+// tasks started while enabled take part in the detection.
+func SetWaitWatchdog(threshold time.Duration, hook func(*StuckWait)) {
+	if threshold <= 0 {
+		_Watchdog.Store(nil)
+		return
+	}
+	_Watchdog.Store(&sWatchdog{threshold: threshold, hook: hook})
+}
+
+// sWaiting a goroutine blocked in a wait on a Defer
+type sWaiting struct {
+	s     *sDefer
+	gid   int64
+	trace []uintptr
+}
+
+var (
+	_WaitsM sync.Mutex
+	_Waits  = map[int64]sWaiting{}
+)
+
+// pRunning record the goroutine running the task of the Defer, for the watchdog
+func (s *sDefer) pRunning() {
+	if _Watchdog.Load() != nil {
+		s.runner.Store(_GoroutineId())
+	}
+}
+
+// pWait wait for the Defer to complete, the error reports a wait cycle, see SetWaitWatchdog
+func (s *sDefer) pWait() Error {
+	s.pObserve()
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	dog := _Watchdog.Load()
+	if dog == nil {
+		<-s.done
+		return nil
+	}
+	self := sWaiting{s: s, gid: _GoroutineId(), trace: pWithTrace()}
+	if err := pEnterWait(self); err != nil {
+		return err
+	}
+	defer pLeaveWait(self.gid)
+	start := time.Now()
+	timer := time.NewTimer(dog.threshold)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+		return nil
+	case <-timer.C:
+	}
+	dog.pReport(s, self.trace, time.Since(start))
+	<-s.done
+	return nil
+}
+
+// pEnterWait register a wait, unless it closes a cycle of waits
+func pEnterWait(self sWaiting) Error {
+	_WaitsM.Lock()
+	defer _WaitsM.Unlock()
+	var path []sWaiting
+	for s := self.s; len(path) < 1024; {
+		runner := s.runner.Load()
+		if runner == 0 {
+			break
+		}
+		if runner == self.gid {
+			return _ErrWaitCycle(self, path)
+		}
+		next, ok := _Waits[runner]
+		if !ok {
+			break
+		}
+		path = append(path, next)
+		s = next.s
+	}
+	_Waits[self.gid] = self
+	return nil
+}
+
+func pLeaveWait(gid int64) {
+	_WaitsM.Lock()
+	delete(_Waits, gid)
+	_WaitsM.Unlock()
+}
+
+// _ErrWaitCycle the error of a wait closing a cycle, nesting the other waits of the cycle
+func _ErrWaitCycle(self sWaiting, path []sWaiting) Error {
+	var err Error
+	for i := len(path) - 1; i >= 0; i-- {
+		info := InfoClean(EInternal, fmt.Sprintf("waiting in goroutine %d", path[i].gid))
+		if err == nil {
+			err = &sErrNode{info: info, trace: path[i].trace}
+		} else {
+			err = &sErrChain{sErrNode: sErrNode{info: info, trace: path[i].trace}, next: err}
+		}
+	}
+	return pErrRoot(InfoClean(EInternal, fmt.Sprintf("wait cycle detected in goroutine %d", self.gid)), self.trace, err)
+}
+
+func (dog *sWatchdog) pReport(s *sDefer, waiter []uintptr, waited time.Duration) {
+	report := &StuckWait{Waited: waited, Waiter: _PC2Frame(waiter)}
+	s.m.Lock()
+	report.Label = s.label
+	s.m.Unlock()
+	if created := s.created; created != nil {
+		report.Creator = _PC2Frame(created)
+	} else if s.creator != nil {
+		report.Creator = _PC2Frame(s.creator)
+	}
+	if runner := s.runner.Load(); runner != 0 {
+		report.Task = _GoroutineStack(runner)
+	}
+	defer func() { _ = recover() }()
+	if dog.hook != nil {
+		dog.hook(report)
+	} else {
+		_, _ = fmt.Fprint(os.Stderr, report.String())
+	}
+}
+
+// _GoroutineId the id of the current goroutine, parsed from the header of its stack
+func _GoroutineId() int64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := bytes.Fields(buf[:n]) // goroutine 123 [running]:
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(string(fields[1]), 10, 64)
+	return id
+}
+
+// _GoroutineStack the current stack of goroutine id, parsed from the dump of all stacks. nil if it does not exist
+func _GoroutineStack(id int64) []StackFrame {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	header := fmt.Sprintf("goroutine %d ", id)
+	for _, block := range strings.Split(string(buf), "\n\n") {
+		if strings.HasPrefix(block, header) {
+			return _ParseStack(strings.Split(block, "\n")[1:])
+		}
+	}
+	return nil
+}
+
+// _ParseStack parse the function and location line pairs of a goroutine stack dump
+func _ParseStack(lines []string) (frames []StackFrame) {
+	for i := 0; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		if paren := strings.LastIndexByte(fn, '('); (paren > 0) && !strings.HasPrefix(fn, "created by ") {
+			fn = fn[:paren]
+		}
+		location := strings.TrimSpace(lines[i+1])
+		if space := strings.IndexByte(location, ' '); space >= 0 {
+			location = location[:space]
+		}
+		frame := StackFrame{Func: fn, File: location}
+		if colon := strings.LastIndexByte(location, ':'); colon >= 0 {
+			frame.File = location[:colon]
+			frame.Line, _ = strconv.Atoi(location[colon+1:])
+		}
+		frames = append(frames, frame)
+	}
+	return
+}
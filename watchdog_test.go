@@ -0,0 +1,94 @@
+package calm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func _IsWaitCycle(err Error) bool {
+	return (err != nil) && (err.ECode() == EInternal) && strings.Contains(PrintCleans(err), "wait cycle detected")
+}
+
+func TestWatchdogCycle(t *testing.T) {
+	SetWaitWatchdog(time.Hour, nil)
+	defer SetWaitWatchdog(0, nil)
+	ready := make(chan struct{})
+	var a, b Defer
+	a = RunAsync(func() {
+		<-ready
+		b.Get()
+	})
+	b = RunAsync(func() {
+		<-ready
+		a.Get()
+	})
+	close(ready)
+	errA, errB := a.Dump(), b.Dump()
+	if !_IsWaitCycle(errA) || !_IsWaitCycle(errB) {
+		t.Fatalf("want both tasks failing on the cycle, got\n%v\n%v", errA, errB)
+	}
+}
+
+func TestWatchdogSelfWait(t *testing.T) {
+	SetWaitWatchdog(time.Hour, nil)
+	defer SetWaitWatchdog(0, nil)
+	ready := make(chan struct{})
+	var d Defer
+	d = RunAsync(func() {
+		<-ready
+		d.Wait()
+	})
+	close(ready)
+	if err := d.Dump(); !_IsWaitCycle(err) {
+		t.Fatalf("want a wait cycle, got %v", err)
+	}
+}
+
+func TestWatchdogStuckWait(t *testing.T) {
+	reports := make(chan *StuckWait, 1)
+	SetWaitWatchdog(20*time.Millisecond, func(w *StuckWait) { reports <- w })
+	defer SetWaitWatchdog(0, nil)
+	gate := make(chan struct{})
+	d := RunAsync(func() { <-gate })
+	d.SetLabel("slow task")
+	waited := make(chan Error)
+	go func() { waited <- d.Dump() }()
+	report := <-reports
+	close(gate)
+	if err := <-waited; err != nil {
+		t.Fatalf("wait failed with %v", err)
+	}
+	if (report.Label != "slow task") || (report.Waited < 20*time.Millisecond) {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if (len(report.Waiter) == 0) || (len(report.Task) == 0) {
+		t.Errorf("missing stacks in report:\n%s", report)
+	}
+	if !strings.Contains(report.String(), "slow task") {
+		t.Errorf("label missing from report:\n%s", report)
+	}
+}
+
+func TestWatchdogCycleFold(t *testing.T) {
+	SetWaitWatchdog(time.Hour, nil)
+	defer SetWaitWatchdog(0, nil)
+	ready := make(chan struct{})
+	var folded, unwrapped Error
+	var d DeferT[int]
+	d = RunAsyncT(func() int {
+		<-ready
+		d.Defer.Unwrap(func(err Error) { unwrapped = err })
+		return d.Fold(func(err Error) int {
+			folded = err
+			return -1
+		})
+	})
+	close(ready)
+	if v, err := d.Dump(); (v != -1) || (err != nil) {
+		t.Fatalf("got %d, %v, want the value of onError", v, err)
+	}
+	if !_IsWaitCycle(folded) || !_IsWaitCycle(unwrapped) {
+		t.Errorf("want the cycle passed to onError, got\n%v\n%v", folded, unwrapped)
+	}
+}
@@ -0,0 +1,478 @@
+package calm
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"unicode/utf8"
+)
+
+// WireVersion version byte leading every binary encoded error. Version 2 added attributes, version 1 payloads are
+// still decoded
+const WireVersion = 2
+
+const (
+	_WireDetail = 1 << iota
+	_WireMeta
+	_WireName
+	_WireFrames
+	_WirePCs
+	_WireNext
+	_WireCauses
+	_WireTrunc
+	_WireAttrs
+)
+
+// _WireKnownFlags the flags defined by each version, indexed by version
+var _WireKnownFlags = [...]uint64{1: _WireTrunc<<1 - 1, 2: _WireAttrs<<1 - 1}
+
+const (
+	_WireMaxDepth = 1024
+	// _WireCleanCut length messages are cut to by the last resort of size capping
+	_WireCleanCut = 32
+)
+
+var (
+	// _PCBase reference point of encoded program counters, making them immune to address space randomization
+	_PCBase = reflect.ValueOf(MakeErrCode).Pointer()
+
+	_BuildOnce sync.Once
+	_BuildID   uint64
+)
+
+// BuildID
+// Identifier of the running binary, attached to raw program counters by the binary codec.
+// Computed from the embedded build information, Go version and target platform.
+// Program counters decoded from a process with the same BuildID are symbolized locally.
+func BuildID() uint64 {
+	_BuildOnce.Do(func() {
+		h := fnv.New64a()
+		if info, ok := debug.ReadBuildInfo(); ok {
+			_, _ = h.Write([]byte(info.String()))
+		}
+		_, _ = h.Write([]byte(runtime.Version() + runtime.GOOS + runtime.GOARCH))
+		_BuildID = h.Sum64()
+	})
+	return _BuildID
+}
+
+func _PCOffsets(pc []uintptr) []int64 {
+	result := make([]int64, len(pc))
+	for i, p := range pc {
+		result[i] = int64(p) - int64(_PCBase)
+	}
+	return result
+}
+
+// EncodeWire
+// Encode err in the compact binary format: the WireVersion byte, the build ID if raw program counters are included,
+// then one varint-based record per slice. option selects the optional parts as for EncodeJSON, nil for CleanEncode,
+// raw program counters with option.PCs. If option.MaxBytes is set and exceeded, the encoder drops in order traces,
+// details and metas, the deepest slices, and finally cuts the clean messages, marking every affected slice as
+// truncated. The top slice codes are always emitted, even if that alone exceeds option.MaxBytes.
+// A nil err encodes to nil.
+func EncodeWire(err Error, option *EncodeOptions) []byte {
+	if err == nil {
+		return nil
+	}
+	if option == nil {
+		option = CleanEncode
+	}
+	node := _ToWire(err, option)
+	data := _WireBytes(node)
+	if (option.MaxBytes <= 0) || (len(data) <= option.MaxBytes) {
+		return data
+	}
+	degrade := []func(n *sWireNode){
+		func(n *sWireNode) { _WireTruncate(n, _WireNoTrace) },
+		func(n *sWireNode) { _WireTruncate(n, _WireNoDetail) },
+	}
+	for _, step := range degrade {
+		step(node)
+		if data = _WireBytes(node); len(data) <= option.MaxBytes {
+			return data
+		}
+	}
+	for depth := _WireDepth(node) - 1; depth > 0; depth-- {
+		_WirePrune(node, depth)
+		if data = _WireBytes(node); len(data) <= option.MaxBytes {
+			return data
+		}
+	}
+	_WireTruncate(node, _WireCutClean)
+	if data = _WireBytes(node); len(data) <= option.MaxBytes {
+		return data
+	}
+	return _WireBytes(&sWireNode{Type: node.Type, Code: node.Code, Trunc: true})
+}
+
+// EncodeWireText
+// Equivalent of EncodeWire in base64url form for HTTP or gRPC metadata headers, option.MaxBytes caps the text size
+func EncodeWireText(err Error, option *EncodeOptions) string {
+	if (option != nil) && (option.MaxBytes > 0) {
+		capped := *option
+		capped.MaxBytes = base64.RawURLEncoding.DecodedLen(option.MaxBytes)
+		option = &capped
+	}
+	return base64.RawURLEncoding.EncodeToString(EncodeWire(err, option))
+}
+
+// DecodeWire
+// Decode an error encoded by EncodeWire. The result is marked as remote, see IsRemote.
+// Raw program counters are symbolized if they come from a binary of the same BuildID, and kept as offsets otherwise.
+// Empty input is decoded as a nil Error.
+func DecodeWire(data []byte) (Error, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	r := &_WireReader{data: data}
+	version := r.Byte()
+	if (version < 1) || (int(version) >= len(_WireKnownFlags)) {
+		return nil, fmt.Errorf("calm: unsupported wire version %d", version)
+	}
+	r.known = _WireKnownFlags[version]
+	if r.Uvarint() == 1 {
+		r.local = r.Uvarint() == BuildID()
+	}
+	node := r.Node(0)
+	if r.err == nil && r.pos != len(r.data) {
+		r.err = errors.New("calm: trailing bytes after wire error")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return _FromWire(node)
+}
+
+// DecodeWireText Equivalent of DecodeWire for the base64url form of EncodeWireText
+func DecodeWireText(text string) (Error, error) {
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeWire(data)
+}
+
+func _WireBytes(node *sWireNode) []byte {
+	b := []byte{WireVersion}
+	if _WireHasPCs(node) {
+		b = binary.AppendUvarint(b, 1)
+		b = binary.AppendUvarint(b, BuildID())
+	} else {
+		b = binary.AppendUvarint(b, 0)
+	}
+	return _WireAppendNode(b, node)
+}
+
+func _WireHasPCs(n *sWireNode) bool {
+	if len(n.PCs) > 0 {
+		return true
+	}
+	if (n.Next != nil) && _WireHasPCs(n.Next) {
+		return true
+	}
+	for _, c := range n.Causes {
+		if _WireHasPCs(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func _WireAppendNode(b []byte, n *sWireNode) []byte {
+	flags := uint64(0)
+	if n.Detail != "" {
+		flags |= _WireDetail
+	}
+	if len(n.Meta) > 0 {
+		flags |= _WireMeta
+	}
+	// names of the system type are known to every process
+	if (n.Type != 0) && (n.Name != "") {
+		flags |= _WireName
+	}
+	if len(n.Frames) > 0 {
+		flags |= _WireFrames
+	}
+	if len(n.PCs) > 0 {
+		flags |= _WirePCs
+	}
+	if len(n.Causes) > 0 {
+		flags |= _WireCauses
+	} else if n.Next != nil {
+		flags |= _WireNext
+	}
+	if n.Trunc {
+		flags |= _WireTrunc
+	}
+	if len(n.Attrs) > 0 {
+		flags |= _WireAttrs
+	}
+	b = binary.AppendUvarint(b, flags)
+	b = binary.AppendUvarint(b, uint64(n.Type))
+	b = binary.AppendUvarint(b, uint64(n.Code))
+	b = _WireAppendString(b, n.Clean)
+	if flags&_WireName != 0 {
+		b = _WireAppendString(b, n.Name)
+	}
+	if flags&_WireDetail != 0 {
+		b = _WireAppendString(b, n.Detail)
+	}
+	if flags&_WireMeta != 0 {
+		b = _WireAppendString(b, string(n.Meta))
+	}
+	if flags&_WireAttrs != 0 {
+		b = binary.AppendUvarint(b, uint64(len(n.Attrs)))
+		for _, key := range _SortedKeys(n.Attrs) {
+			b = _WireAppendString(b, key)
+			b = _WireAppendString(b, string(n.Attrs[key]))
+		}
+	}
+	if flags&_WireFrames != 0 {
+		b = binary.AppendUvarint(b, uint64(len(n.Frames)))
+		for _, f := range n.Frames {
+			b = _WireAppendString(b, f.Func)
+			b = _WireAppendString(b, f.File)
+			b = binary.AppendUvarint(b, uint64(f.Line))
+		}
+	}
+	if flags&_WirePCs != 0 {
+		b = binary.AppendUvarint(b, uint64(len(n.PCs)))
+		for _, pc := range n.PCs {
+			b = binary.AppendVarint(b, pc)
+		}
+	}
+	if flags&_WireCauses != 0 {
+		b = binary.AppendUvarint(b, uint64(len(n.Causes)))
+		for _, c := range n.Causes {
+			b = _WireAppendNode(b, c)
+		}
+	} else if flags&_WireNext != 0 {
+		b = _WireAppendNode(b, n.Next)
+	}
+	return b
+}
+
+func _WireAppendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func _WireTruncate(n *sWireNode, apply func(n *sWireNode) bool) {
+	if apply(n) {
+		n.Trunc = true
+	}
+	if n.Next != nil {
+		_WireTruncate(n.Next, apply)
+	}
+	for _, c := range n.Causes {
+		_WireTruncate(c, apply)
+	}
+}
+
+func _WireNoTrace(n *sWireNode) bool {
+	dropped := (len(n.Frames) > 0) || (len(n.PCs) > 0)
+	n.Frames, n.PCs = nil, nil
+	return dropped
+}
+
+func _WireNoDetail(n *sWireNode) bool {
+	dropped := (n.Detail != "") || (len(n.Meta) > 0) || (len(n.Attrs) > 0)
+	n.Detail, n.Meta, n.Attrs = "", nil, nil
+	return dropped
+}
+
+func _WireCutClean(n *sWireNode) bool {
+	if len(n.Clean) <= _WireCleanCut {
+		return false
+	}
+	cut := _WireCleanCut
+	for (cut > 0) && !utf8.RuneStart(n.Clean[cut]) {
+		cut--
+	}
+	n.Clean = n.Clean[:cut]
+	return true
+}
+
+func _WireDepth(n *sWireNode) int {
+	depth := 0
+	if n.Next != nil {
+		depth = _WireDepth(n.Next)
+	}
+	for _, c := range n.Causes {
+		if d := _WireDepth(c); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+// _WirePrune drop the slices nested deeper than depth levels, marking the slices they were cut from
+func _WirePrune(n *sWireNode, depth int) {
+	if depth <= 1 {
+		if (n.Next != nil) || (len(n.Causes) > 0) {
+			n.Next, n.Causes, n.Trunc = nil, nil, true
+		}
+		return
+	}
+	if n.Next != nil {
+		_WirePrune(n.Next, depth-1)
+	}
+	for _, c := range n.Causes {
+		_WirePrune(c, depth-1)
+	}
+}
+
+type _WireReader struct {
+	data  []byte
+	pos   int
+	local bool
+	known uint64 // flags defined by the version of the payload
+	err   error
+}
+
+func (r *_WireReader) fail(msg string) {
+	if r.err == nil {
+		r.err = errors.New("calm: malformed wire error, " + msg)
+	}
+}
+
+func (r *_WireReader) Byte() byte {
+	if r.pos >= len(r.data) {
+		r.fail("unexpected end")
+		return 0
+	}
+	r.pos++
+	return r.data[r.pos-1]
+}
+
+func (r *_WireReader) Uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *_WireReader) Varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *_WireReader) U32() uint32 {
+	v := r.Uvarint()
+	if v > uint64(^uint32(0)) {
+		r.fail("code overflow")
+	}
+	return uint32(v)
+}
+
+// Count a length prefix, bounded by the remaining input as every item takes at least one byte
+func (r *_WireReader) Count() int {
+	v := r.Uvarint()
+	if v > uint64(len(r.data)-r.pos) {
+		r.fail("length out of range")
+		return 0
+	}
+	return int(v)
+}
+
+func (r *_WireReader) String() string {
+	n := r.Count()
+	if r.err != nil {
+		return ""
+	}
+	r.pos += n
+	return string(r.data[r.pos-n : r.pos])
+}
+
+func (r *_WireReader) Node(depth int) *sWireNode {
+	if depth > _WireMaxDepth {
+		r.fail("nesting too deep")
+		return nil
+	}
+	flags := r.Uvarint()
+	if unknown := flags &^ r.known; unknown != 0 {
+		r.fail(fmt.Sprintf("unknown flags %#x", unknown))
+		return nil
+	}
+	n := &sWireNode{Type: r.U32(), Code: r.U32(), Trunc: flags&_WireTrunc != 0}
+	n.Clean = r.String()
+	n.Name = _PrintableErrorTag(&sInfoCode{fCode: MakeErrCode(n.Type, n.Code)})
+	if flags&_WireName != 0 {
+		n.Name = r.String()
+	}
+	if flags&_WireDetail != 0 {
+		n.Detail = r.String()
+	}
+	if flags&_WireMeta != 0 {
+		n.Meta = []byte(r.String())
+	}
+	if flags&_WireAttrs != 0 {
+		count := r.Count()
+		n.Attrs = make(map[string]json.RawMessage, count)
+		for i := 0; (i < count) && (r.err == nil); i++ {
+			key := r.String()
+			n.Attrs[key] = []byte(r.String())
+		}
+	}
+	if flags&_WireFrames != 0 {
+		count := r.Count()
+		for i := 0; (i < count) && (r.err == nil); i++ {
+			n.Frames = append(n.Frames, StackFrame{Func: r.String(), File: r.String(), Line: int(r.Uvarint())})
+		}
+	}
+	if flags&_WirePCs != 0 {
+		count := r.Count()
+		pcs := make([]int64, 0, count)
+		for i := 0; (i < count) && (r.err == nil); i++ {
+			pcs = append(pcs, r.Varint())
+		}
+		n.Frames = append(n.Frames, r.Symbolize(pcs)...)
+	}
+	if flags&_WireCauses != 0 {
+		count := r.Count()
+		for i := 0; (i < count) && (r.err == nil); i++ {
+			n.Causes = append(n.Causes, r.Node(depth+1))
+		}
+	} else if flags&_WireNext != 0 {
+		n.Next = r.Node(depth + 1)
+	}
+	return n
+}
+
+// Symbolize resolve program counter offsets with the local binary if it is the sending one, or keep them as is
+func (r *_WireReader) Symbolize(pcs []int64) []StackFrame {
+	if r.local {
+		pc := make([]uintptr, len(pcs))
+		for i, off := range pcs {
+			pc[i] = uintptr(off + int64(_PCBase))
+		}
+		return _PC2Frame(pc)
+	}
+	frames := make([]StackFrame, len(pcs))
+	for i, off := range pcs {
+		frames[i] = StackFrame{Func: fmt.Sprintf("pc%+#x", off), File: "?"}
+	}
+	return frames
+}
@@ -0,0 +1,231 @@
+package calm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _KeyTenant = NewKey[string]("tenant")
+
+func TestWireVersion1(t *testing.T) {
+	data := EncodeWire(ErrCleanN(ErrClean(EStgNone, "row"), EResNone, "user"), FullEncode)
+	data[0] = 1
+	err, e := DecodeWire(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if slices := err.Slices(); (len(slices) != 2) || (slices[1].Clean() != "row") {
+		t.Errorf("decoded %v", err)
+	}
+}
+
+func TestWireUnknownFlags(t *testing.T) {
+	data := EncodeWire(ErrAttr(ErrClean(EResNone, "user"), _KeyTenant.Of("acme")), FullEncode)
+	data[0] = 1 // attributes are not defined in version 1
+	if _, e := DecodeWire(data); (e == nil) || !strings.Contains(e.Error(), "unknown flags") {
+		t.Errorf("got %v, want unknown flags", e)
+	}
+	// version, no build ID, flags 1<<9, type, code, empty clean message
+	data = []byte{WireVersion, 0, 0x80, 0x04, 0, ERequest, 0}
+	if _, e := DecodeWire(data); (e == nil) || !strings.Contains(e.Error(), "unknown flags") {
+		t.Errorf("got %v, want unknown flags", e)
+	}
+}
+
+// _WireSlice the comparable parts of a slice visited by Walk
+type _WireSlice struct {
+	depth       int
+	code        uint64
+	name, clean string
+	detail      string
+}
+
+func _WireSlices(err Error) (res []_WireSlice) {
+	Walk(err, func(depth int, info ErrorInfo) bool {
+		res = append(res, _WireSlice{
+			depth: depth, code: MakeErrCode(info.TCode(), info.ECode()),
+			name: _PrintableErrorTag(info), clean: info.Clean(), detail: info.Detail(),
+		})
+		return true
+	})
+	return
+}
+
+func _Truncated(err Error) (res []bool) {
+	Walk(err, func(_ int, info ErrorInfo) bool {
+		res = append(res, info.(*sInfoRemote).truncated)
+		return true
+	})
+	return
+}
+
+func _WireRoundTrip(t *testing.T, err Error, option *EncodeOptions) Error {
+	t.Helper()
+	decoded, e := DecodeWire(EncodeWire(err, option))
+	if e != nil {
+		t.Fatal(e)
+	}
+	text, e := DecodeWireText(EncodeWireText(err, option))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if a, b := _WireSlices(decoded), _WireSlices(text); len(a) != len(b) {
+		t.Fatalf("binary and text forms differ: %v, %v", a, b)
+	}
+	return decoded
+}
+
+func TestWireRoundTrip(t *testing.T) {
+	user := ErrAttr(ErrDetail(EResNone, "user not found", "user 42 not in shard 3"), _KeyTenant.Of("acme"))
+	custom := ErrCleanN(ErrRetryAfter(EResRetry, "busy", 3*time.Second), MakeErrCode(7, 3), "custom")
+	err := ErrCleanN(Join(user, custom), ERequest, "lookup failed")
+	decoded := _WireRoundTrip(t, err, FullEncode)
+	want, got := _WireSlices(err), _WireSlices(decoded)
+	if len(want) != len(got) {
+		t.Fatalf("slices %v, want %v", got, want)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("slice %d: %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if tenant, ok := Lookup(decoded, _KeyTenant); !ok || (tenant != "acme") {
+		t.Errorf("attribute %q %v, want acme", tenant, ok)
+	}
+	if meta, ok := FindMeta[json.RawMessage](decoded); !ok || (string(meta) != "3000000000") {
+		t.Errorf("meta %s %v, want the retry delay", meta, ok)
+	}
+}
+
+func TestWireCleanEncode(t *testing.T) {
+	err := ErrAttr(ErrDetail(EResNone, "user not found", "user 42 not in shard 3"), _KeyTenant.Of("acme"))
+	decoded := _WireRoundTrip(t, err, nil)
+	if detail := decoded.Slices()[0].Detail(); detail != "user not found" {
+		t.Errorf("detail %q leaked", detail)
+	}
+	if _, ok := Lookup(decoded, _KeyTenant); ok {
+		t.Error("attribute leaked")
+	}
+}
+
+func _DecodedFrames(t *testing.T, data []byte) []StackFrame {
+	t.Helper()
+	decoded, e := DecodeWire(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return decoded.(interface{ pFrames() []StackFrame }).pFrames()
+}
+
+func TestWirePCs(t *testing.T) {
+	err := ErrClean(EInternal, "traced")
+	data := EncodeWire(err, &EncodeOptions{PCs: true})
+	if data[1] != 1 {
+		t.Fatal("build ID missing")
+	}
+	local := _DecodedFrames(t, data)
+	found := false
+	for _, f := range local {
+		found = found || (f.Func == "testing.tRunner")
+	}
+	if !found {
+		t.Errorf("frames not symbolized locally: %v", local)
+	}
+	data[2] ^= 1 // low bits of the build ID
+	for _, f := range _DecodedFrames(t, data) {
+		if !strings.HasPrefix(f.Func, "pc") || (f.File != "?") {
+			t.Errorf("foreign frame symbolized: %+v", f)
+		}
+	}
+}
+
+func TestWireMaxBytes(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	root := ErrDetail(EInternal, "root "+long, "root detail "+long)
+	err := ErrAttr(ErrRetryAfterN(ErrCleanN(root, EStgFail, "middle"), EResRetry, "top", time.Second), _KeyTenant.Of(long))
+	full := len(EncodeWire(err, FullEncode))
+	clean := len(EncodeWire(err, CleanEncode))
+	// each truncated slice may take one more byte of flags
+	noTrace := len(EncodeWire(err, &EncodeOptions{Detail: true, Meta: true, Attrs: true})) + 3
+	for _, c := range []struct {
+		name     string
+		maxBytes int
+		check    func(decoded Error) bool
+	}{
+		{"traces", noTrace, func(d Error) bool {
+			return (len(d.Slices()) == 3) && (len(d.Slices()[2].Detail()) > len(d.Slices()[2].Clean()))
+		}},
+		{"details", clean + 3, func(d Error) bool {
+			_, found := Lookup(d, _KeyTenant)
+			return (len(d.Slices()) == 3) && !found
+		}},
+		{"slices", clean - 100, func(d Error) bool { return len(d.Slices()) < 3 }},
+		{"depth", 12, func(d Error) bool { return len(d.Slices()) == 1 }},
+	} {
+		if (c.maxBytes >= full) || (c.maxBytes <= 0) {
+			t.Fatalf("%s: bad cap %d for %d bytes", c.name, c.maxBytes, full)
+		}
+		data := EncodeWire(err, &EncodeOptions{Detail: true, Meta: true, Frames: true, Attrs: true, MaxBytes: c.maxBytes})
+		if len(data) > c.maxBytes {
+			t.Errorf("%s: %d bytes over the %d cap", c.name, len(data), c.maxBytes)
+		}
+		decoded, e := DecodeWire(data)
+		if e != nil {
+			t.Fatalf("%s: %v", c.name, e)
+		}
+		if !c.check(decoded) {
+			t.Errorf("%s: unexpected result %v", c.name, decoded)
+		}
+		marked := false
+		for _, trunc := range _Truncated(decoded) {
+			marked = marked || trunc
+		}
+		if !marked {
+			t.Errorf("%s: no slice marked truncated", c.name)
+		}
+	}
+	data := EncodeWire(ErrClean(ERequest, long), &EncodeOptions{MaxBytes: 48})
+	if decoded, e := DecodeWire(data); (e != nil) || (len(decoded.Slices()[0].Clean()) > _WireCleanCut) ||
+		!_Truncated(decoded)[0] {
+		t.Errorf("cut clean: %v %v", decoded, e)
+	}
+	data = EncodeWire(err, &EncodeOptions{MaxBytes: 1})
+	decoded, e := DecodeWire(data)
+	if (e != nil) || (decoded.ECode() != EResRetry) || !_Truncated(decoded)[0] {
+		t.Errorf("codes only: %v %v", decoded, e)
+	}
+}
+
+func TestWireMalformed(t *testing.T) {
+	data := EncodeWire(ErrCleanN(Join(ErrClean(ETimeout, "a"), ErrClean(ECancel, "b")), ERequest, "c"), FullEncode)
+	for n := 1; n < len(data); n++ {
+		if _, e := DecodeWire(data[:n]); e == nil {
+			t.Errorf("prefix of %d bytes accepted", n)
+		}
+	}
+	trailing := append(append([]byte(nil), data...), 0)
+	if _, e := DecodeWire(trailing); (e == nil) || !strings.Contains(e.Error(), "trailing") {
+		t.Errorf("trailing bytes: %v", e)
+	}
+	for _, version := range []byte{0, WireVersion + 1} {
+		bad := append([]byte{version}, data[1:]...)
+		if _, e := DecodeWire(bad); (e == nil) || !strings.Contains(e.Error(), "version") {
+			t.Errorf("version %d: %v", version, e)
+		}
+	}
+	// clean message claiming 2^28 bytes
+	oversized := []byte{WireVersion, 0, 0, 0, ERequest, 0x80, 0x80, 0x80, 0x80, 0x01}
+	if _, e := DecodeWire(oversized); (e == nil) || !strings.Contains(e.Error(), "out of range") {
+		t.Errorf("oversized length: %v", e)
+	}
+	deep := []byte{WireVersion, 0}
+	for i := 0; i <= _WireMaxDepth+1; i++ {
+		deep = append(deep, _WireNext, 0, ERequest, 0)
+	}
+	deep = append(deep, 0, 0, ERequest, 0)
+	if _, e := DecodeWire(deep); (e == nil) || !strings.Contains(e.Error(), "too deep") {
+		t.Errorf("deep nesting: %v", e)
+	}
+}